@@ -0,0 +1,55 @@
+package client
+
+import "sync"
+
+// marshalCache stores the YAML-marshaled form of configuration values so
+// repeated GetConfig calls for an unchanged key skip re-marshaling. Entries
+// are tagged with the content hash of the repository data they were built
+// from and are dropped whenever a refresh changes that hash.
+type marshalCache struct {
+	mu      sync.RWMutex
+	hash    string
+	entries map[string][]byte
+}
+
+// get returns the cached bytes for name along with the content hash the cache
+// currently reflects. The hash must be passed back to put.
+func (m *marshalCache) get(name string) (data []byte, hash string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok = m.entries[name]
+	return data, m.hash, ok
+}
+
+// put stores data for name if the cache still reflects hash. A refresh that
+// happened between get and put changes the hash, so stale bytes are dropped.
+func (m *marshalCache) put(name, hash string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hash != hash {
+		return
+	}
+	if m.entries == nil {
+		m.entries = make(map[string][]byte)
+	}
+	m.entries[name] = data
+}
+
+// invalidate drops all entries if hash differs from the current content hash.
+func (m *marshalCache) invalidate(hash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hash == hash {
+		return
+	}
+	m.hash = hash
+	m.entries = nil
+}
+
+// version returns the content hash the cache currently reflects, which
+// changes whenever a refresh changes the repository content.
+func (m *marshalCache) version() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.hash
+}
@@ -1,15 +1,27 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"net/url"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/sardine-ai/go-remote-config/schedule"
 	"github.com/sardine-ai/go-remote-config/source"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,6 +34,56 @@ type Client struct {
 	// Thread-safe closed state using atomic operations
 	closed atomic.Bool
 
+	// Coalesces concurrent refreshes into a single backend call
+	refreshGroup singleflight.Group
+
+	// Set once the client is cloned; shared with its clones, see Clone
+	group atomic.Pointer[cloneGroup]
+
+	// Marshaled values keyed by config name, invalidated on content change
+	cache marshalCache
+
+	// When set, missing keys yield the default value with a nil error
+	allowMissingKeys bool
+
+	// When set, unknown fields are an error when decoding into structs
+	strict bool
+
+	// Separates the keys of a nested path in config names, see KeyDelimiter
+	keyDelimiter      string
+	preferLiteralKeys bool
+
+	// Lazy clients defer the initial refresh until the first read
+	lazy     bool
+	lazyOnce sync.Once
+
+	// Closed on the first successful refresh; reads of missing keys wait
+	// up to waitForLoad for it
+	loaded      chan struct{}
+	loadedOnce  sync.Once
+	waitForLoad time.Duration
+
+	// Decides when the background refresh runs; guarded by mu
+	scheduler schedule.Scheduler
+
+	// Wakes the background refresh to reschedule, see SetRefreshInterval
+	reschedule chan struct{}
+
+	// Decrypts values read with GetConfigDecrypt
+	decryptor func(ciphertext []byte) ([]byte, error)
+
+	// Called with the duration and outcome of every refresh
+	refreshObserver func(repoName string, d time.Duration, err error)
+
+	// Serves reads until the first successful remote refresh; guarded by mu
+	bootstrap source.Repository
+
+	// Closed by the next successful refresh, created on demand; guarded by mu
+	refreshed chan struct{}
+
+	// Closed when the client's context is done
+	done <-chan struct{}
+
 	// Staleness tracking for refresh failures
 	mu              sync.RWMutex
 	lastRefreshTime time.Time
@@ -30,6 +92,10 @@ type Client struct {
 	refreshErrors   int64
 }
 
+// ErrConfigNotFound is returned by the GetConfig* methods when the requested
+// key is not present in the repository, unless AllowMissingKeys is set.
+var ErrConfigNotFound = errors.New("config not found")
+
 var (
 	defaultClient   *Client
 	defaultClientMu sync.RWMutex
@@ -59,8 +125,104 @@ type ClientOptions struct {
 	// default client for package-level functions like GetConfig().
 	// Defaults to true for backwards compatibility with NewClient().
 	SetAsDefault bool
+
+	// Watch starts a long-poll loop alongside the periodic refresh when the
+	// repository implements source.Watcher (e.g. a WebRepository with a
+	// WatchURL pointing at a config server's /watch endpoint). The client
+	// refreshes as soon as the source reports a change instead of waiting
+	// for the next tick.
+	Watch bool
+
+	// AllowMissingKeys makes the GetConfig* methods return the default value
+	// with a nil error when a key is missing, instead of ErrConfigNotFound.
+	// Use it when an absent key simply means "use the default". Defaults to
+	// false, so missing keys are reported as errors.
+	AllowMissingKeys bool
+
+	// Lazy defers the initial refresh until the first GetConfig* call instead
+	// of performing it in NewClientWithOptions, which saves backend calls for
+	// clients that may never be read. The periodic refresh still starts
+	// immediately. Because the initial refresh is deferred, its error is not
+	// returned by the constructor; use GetRefreshStatus to inspect it.
+	Lazy bool
+
+	// Scheduler decides when the background refresh runs, e.g.
+	// schedule.MustCron("*/5 * * * *") to refresh on five-minute boundaries.
+	// Defaults to schedule.Every(refreshInterval). The refresh interval is
+	// still used to decide when data is stale, so set it to the longest
+	// expected gap between refreshes.
+	Scheduler schedule.Scheduler
+
+	// Decryptor decrypts values read with GetConfigDecrypt, e.g. with a KMS
+	// key, so secrets can stay encrypted in the source.
+	Decryptor func(ciphertext []byte) ([]byte, error)
+
+	// Strict makes decoding into structs fail when the config has fields the
+	// struct doesn't declare, so a typo in a config key is reported by
+	// GetConfig (and the other struct-decoding methods) instead of being
+	// silently ignored. Defaults to false.
+	Strict bool
+
+	// WaitForLoad makes reads of a missing key wait up to this long for the
+	// first successful refresh before reporting it missing, so reads racing
+	// a slow or failed startup (e.g. with Lazy or BootstrapPath) resolve
+	// once config loads instead of returning ErrConfigNotFound. Reads after
+	// the first successful refresh never wait. Defaults to 0 (no waiting).
+	WaitForLoad time.Duration
+
+	// BootstrapPath is a local YAML file loaded before the first remote
+	// refresh, so the client can start when the remote source is briefly
+	// unavailable. Its values are served until a remote refresh succeeds; if
+	// the initial remote refresh fails, NewClientWithOptions logs the error
+	// and returns the bootstrapped client instead of failing. A bootstrap
+	// file that cannot be loaded is logged and ignored.
+	BootstrapPath string
+
+	// KeyDelimiter lets config names address nested values: with "." the
+	// name "feature.v2.enabled" reads enabled from the feature map's v2
+	// map. A top-level key that contains the delimiter itself (a literal
+	// "feature.v2.enabled: true") is still found; when both exist, the
+	// nested value wins unless PreferLiteralKeys is set. Defaults to "",
+	// which treats every name as a literal top-level key.
+	KeyDelimiter string
+
+	// PreferLiteralKeys makes a top-level key equal to the whole name take
+	// precedence over the nested path when KeyDelimiter is set.
+	PreferLiteralKeys bool
+
+	// InitialRetries retries a failed initial refresh in
+	// NewClientWithOptions up to this many times before returning its
+	// error, so a transient failure at startup does not leave the client
+	// without config until the first tick. Retries back off exponentially
+	// from InitialRetryBackoff, capped at 30 seconds, with jitter so many
+	// instances starting together do not retry in lockstep. Canceling ctx
+	// stops retrying. Defaults to 0 (no retries); ignored with Lazy.
+	InitialRetries int
+
+	// InitialRetryBackoff is the delay before the first retry of the
+	// initial refresh. Defaults to 1 second.
+	InitialRetryBackoff time.Duration
+
+	// RefreshObserver, when set, is called after every call to the
+	// repository's Refresh with the repository name, how long it took and
+	// its error, e.g. to feed a Prometheus histogram or an OpenTelemetry
+	// instrument. It runs on the goroutine that refreshed, so it should not
+	// block. Clones sharing the repository observe each other's refreshes.
+	RefreshObserver func(repoName string, d time.Duration, err error)
 }
 
+// defaultInitialRetryBackoff is the delay before the first retry of the
+// initial refresh when InitialRetryBackoff is not set.
+const defaultInitialRetryBackoff = time.Second
+
+// maxInitialRetryBackoff caps the delay between retries of the initial refresh.
+const maxInitialRetryBackoff = 30 * time.Second
+
+// watchRetryDelay is how long the watch loop waits after a failed watch
+// request, or a reported change the refresh did not pick up, before trying
+// again. The periodic refresh keeps running meanwhile.
+const watchRetryDelay = 5 * time.Second
+
 // DefaultClientOptions returns the default options used by NewClient().
 func DefaultClientOptions() ClientOptions {
 	return ClientOptions{
@@ -82,58 +244,259 @@ func NewClientWithOptions(ctx context.Context, repository source.Repository, ref
 
 	// Create the Client instance with the provided repository and refresh interval.
 	client := &Client{
-		Repository:      repository,
-		RefreshInterval: refreshInterval,
-		cancel:          cancel,
+		Repository:        repository,
+		RefreshInterval:   refreshInterval,
+		cancel:            cancel,
+		done:              ctx.Done(),
+		allowMissingKeys:  opts.AllowMissingKeys,
+		strict:            opts.Strict,
+		keyDelimiter:      opts.KeyDelimiter,
+		preferLiteralKeys: opts.PreferLiteralKeys,
+		lazy:              opts.Lazy,
+		loaded:            make(chan struct{}),
+		waitForLoad:       opts.WaitForLoad,
+		scheduler:         opts.Scheduler,
+		reschedule:        make(chan struct{}, 1),
+		decryptor:         opts.Decryptor,
+		refreshObserver:   opts.RefreshObserver,
+	}
+
+	if opts.BootstrapPath != "" {
+		client.loadBootstrap(opts.BootstrapPath)
 	}
 
 	// Refresh the configuration data for the first time to ensure the
 	// Client is initialized with the latest data before it is used.
-	err := client.Repository.Refresh()
-	if err != nil {
-		logrus.WithError(err).Error("error refreshing repository")
-		client.recordRefreshError(err)
-		return nil, err
+	// Lazy clients do this on first read instead.
+	if !opts.Lazy {
+		if err := client.initialRefresh(ctx, opts); err != nil {
+			if !client.GetRefreshStatus().Bootstrapped {
+				cancel()
+				return nil, err
+			}
+			logrus.WithError(err).Warn("initial refresh failed, serving bootstrap config")
+		}
 	}
-	client.recordRefreshSuccess()
 
+	client.start(ctx, opts)
+	return client, nil
+}
+
+// initialRefresh refreshes the repository, retrying up to InitialRetries
+// times with jittered exponential backoff while it fails.
+func (c *Client) initialRefresh(ctx context.Context, opts ClientOptions) error {
+	backoff := opts.InitialRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialRetryBackoff
+	}
+	err := c.refreshOnce()
+	for attempt := 0; err != nil && attempt < opts.InitialRetries; attempt++ {
+		// Wait between half and all of the backoff
+		delay := backoff/2 + rand.N(backoff/2+1)
+		logrus.WithError(err).Warnf("initial refresh failed, retrying in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, maxInitialRetryBackoff)
+		err = c.refreshOnce()
+	}
+	return err
+}
+
+// start launches the background refresh (and watch) goroutines and sets the
+// client as the default client if requested.
+func (c *Client) start(ctx context.Context, opts ClientOptions) {
 	// Start the background refresh goroutine
-	go refresh(ctx, client)
+	go refresh(ctx, c)
+
+	if opts.Watch {
+		if watcher, ok := c.Repository.(source.Watcher); ok {
+			go watch(ctx, c, watcher)
+		} else {
+			logrus.Warn("repository does not support watching, relying on periodic refresh")
+		}
+	}
 
 	// Only set as default if requested
 	if opts.SetAsDefault {
 		defaultClientMu.Lock()
-		defaultClient = client
+		defaultClient = c
 		defaultClientMu.Unlock()
 	}
+}
 
-	return client, nil
+// loadBootstrap loads the bootstrap file at path and serves it until the
+// first successful remote refresh.
+func (c *Client) loadBootstrap(path string) {
+	bootstrap := &source.FileRepository{Name: c.Repository.GetName(), Path: path}
+	if err := bootstrap.Refresh(); err != nil {
+		logrus.WithError(err).Warn("error loading bootstrap config")
+		return
+	}
+	c.mu.Lock()
+	c.bootstrap = bootstrap
+	c.mu.Unlock()
+	c.cache.invalidate(source.ContentHash(bootstrap.GetRawData()))
 }
 
-// refresh is a goroutine that periodically refreshes the configuration data
-// from the repository based on the provided refresh interval. It stops
-// refreshing when the given context is canceled.
+// activeRepository returns the repository reads are served from: the
+// bootstrap repository until the first successful refresh, then Repository.
+func (c *Client) activeRepository() source.Repository {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bootstrap != nil {
+		return c.bootstrap
+	}
+	return c.Repository
+}
+
+// refresh is a goroutine that refreshes the configuration data from the
+// repository whenever the client's scheduler fires. It stops refreshing when
+// the given context is canceled.
 func refresh(ctx context.Context, client *Client) {
-	ticker := time.NewTicker(client.RefreshInterval) // Create a new ticker with the given refresh interval
-	defer ticker.Stop()                              // Stop the ticker when the goroutine exits to prevent resource leak
+	// Consult the current scheduler on every tick, so SetRefreshInterval
+	// takes effect once it resets the wait
+	scheduler := schedule.SchedulerFunc(func(now time.Time) time.Time {
+		return client.currentScheduler().Next(now)
+	})
+	schedule.RunWithReset(ctx, scheduler, client.reschedule, func() {
+		// Errors are logged and recorded by refreshShared.
+		_ = client.refreshShared()
+	})
+}
+
+// currentScheduler returns the scheduler the background refresh follows:
+// the Scheduler option, or a fixed RefreshInterval.
+func (c *Client) currentScheduler() schedule.Scheduler {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.scheduler != nil {
+		return c.scheduler
+	}
+	return schedule.Every(c.RefreshInterval)
+}
+
+// SetRefreshInterval changes how often the background refresh runs, e.g. to
+// back off from a struggling source during an incident, without recreating
+// the client and losing its state. The pending wait is abandoned and the next
+// refresh runs d from now, then every d. It replaces any Scheduler option and
+// is also used to decide when data is stale. Non-positive intervals are
+// ignored.
+func (c *Client) SetRefreshInterval(d time.Duration) {
+	if d <= 0 {
+		logrus.WithField("interval", d).Warn("ignoring non-positive refresh interval")
+		return
+	}
+	c.mu.Lock()
+	c.RefreshInterval = d
+	c.scheduler = schedule.Every(d)
+	c.mu.Unlock()
+
+	// Wake the refresh loop; a reset already pending covers this change too
+	select {
+	case c.reschedule <- struct{}{}:
+	default:
+	}
+}
+
+// watch is a goroutine that blocks on the repository's watcher and refreshes
+// the configuration data as soon as a change is reported. It stops when the
+// given context is canceled.
+func watch(ctx context.Context, client *Client, watcher source.Watcher) {
 	for {
-		select {
-		case <-ticker.C:
-			// The ticker has ticked, indicating it's time to refresh the data
-			err := client.Repository.Refresh() // Call the Refresh method of the repository to update the configuration data
-			if err != nil {
-				logrus.WithError(err).Error("error refreshing repository")
-				client.recordRefreshError(err)
-			} else {
-				client.recordRefreshSuccess()
+		changed, err := watcher.WaitForChange(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("error watching repository")
+			select {
+			case <-time.After(watchRetryDelay):
+			case <-ctx.Done():
+				return
 			}
+			continue
+		}
+		if !changed {
+			continue
+		}
+		before := source.ContentHash(client.Repository.GetRawData())
+		err = client.refreshShared()
+		if err == nil && source.ContentHash(client.Repository.GetRawData()) != before {
+			continue
+		}
+		// The refresh failed or did not see the reported change, so the
+		// next watch would likely return at once too; back off rather than
+		// refreshing against the server in a tight loop.
+		select {
+		case <-time.After(watchRetryDelay):
 		case <-ctx.Done():
-			// The context is canceled, indicating the refresh routine should stop
 			return
 		}
 	}
 }
 
+// refreshShared refreshes the repository, coalescing concurrent callers into a
+// single call to Repository.Refresh. Every caller receives the shared result.
+func (c *Client) refreshShared() error {
+	_, err, _ := c.flight().Do("refresh", func() (interface{}, error) {
+		return nil, c.refreshOnce()
+	})
+	return err
+}
+
+// refreshOnce calls Repository.Refresh, records the outcome on the client
+// and any clones sharing the repository, and reports its duration to their
+// refresh observers.
+func (c *Client) refreshOnce() error {
+	start := time.Now()
+	err := source.SafeRefresh(c.Repository)
+	elapsed := time.Since(start)
+	for _, member := range c.members() {
+		if member.refreshObserver != nil {
+			member.refreshObserver(c.Repository.GetName(), elapsed, err)
+		}
+	}
+	if err != nil {
+		logrus.WithError(err).Error("error refreshing repository")
+		for _, member := range c.members() {
+			member.recordRefreshError(err)
+		}
+		return err
+	}
+	hash := source.ContentHash(c.Repository.GetRawData())
+	for _, member := range c.members() {
+		member.recordRefreshSuccess()
+		member.cache.invalidate(hash)
+	}
+	return nil
+}
+
+// RefreshNow forces an immediate refresh of the repository and waits for it
+// to complete or for ctx to be done. Concurrent calls (including a refresh
+// started by the background goroutine) are coalesced into a single backend
+// call and share its result, which protects the backing store when many
+// goroutines notice stale config at once.
+//
+// If ctx is done before the refresh completes, RefreshNow returns ctx.Err();
+// the shared refresh keeps running and its result is still recorded.
+func (c *Client) RefreshNow(ctx context.Context) error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	result := c.flight().DoChan("refresh", func() (interface{}, error) {
+		return nil, c.refreshOnce()
+	})
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // recordRefreshSuccess records a successful refresh operation.
 func (c *Client) recordRefreshSuccess() {
 	c.mu.Lock()
@@ -141,6 +504,14 @@ func (c *Client) recordRefreshSuccess() {
 	c.lastRefreshTime = time.Now()
 	c.lastRefreshErr = nil
 	c.refreshCount++
+	c.bootstrap = nil
+	if c.refreshed != nil {
+		close(c.refreshed)
+		c.refreshed = nil
+	}
+	if c.loaded != nil {
+		c.loadedOnce.Do(func() { close(c.loaded) })
+	}
 }
 
 // recordRefreshError records a failed refresh operation.
@@ -159,6 +530,7 @@ type RefreshStatus struct {
 	RefreshErrors   int64
 	IsStale         bool
 	StaleDuration   time.Duration
+	Bootstrapped    bool // Serving the BootstrapPath file; no remote refresh has succeeded yet
 }
 
 // GetRefreshStatus returns the current refresh status of the client.
@@ -172,6 +544,7 @@ func (c *Client) GetRefreshStatus() RefreshStatus {
 		LastRefreshErr:  c.lastRefreshErr,
 		RefreshCount:    c.refreshCount,
 		RefreshErrors:   c.refreshErrors,
+		Bootstrapped:    c.bootstrap != nil,
 	}
 
 	// Consider stale if last refresh was more than 2x the refresh interval ago
@@ -183,6 +556,15 @@ func (c *Client) GetRefreshStatus() RefreshStatus {
 	return status
 }
 
+// Err returns the error of the most recent refresh, or nil if it succeeded
+// (or no refresh has run yet). It lets embedders poll whether the config is
+// currently degraded; the client keeps serving the last good data meanwhile.
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastRefreshErr
+}
+
 // IsHealthy returns true if the client has fresh config data.
 // Only checks staleness, not last refresh error, to avoid pod restarts
 // on transient errors (e.g., brief S3 outage). Use GetRefreshStatus()
@@ -195,6 +577,28 @@ func (c *Client) IsHealthy() bool {
 	return !status.IsStale
 }
 
+// HealthCheck returns nil if the client's last refresh succeeded and its
+// data is not stale, and an error describing the problem otherwise, for use
+// in an embedder's own health endpoint. Unlike IsHealthy, a failed refresh
+// makes the client unhealthy even while the data is still fresh, like the
+// server's default health check; the refresh error is wrapped so it can be
+// checked with errors.Is.
+func (c *Client) HealthCheck() error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	status := c.GetRefreshStatus()
+	switch {
+	case status.LastRefreshErr != nil:
+		return fmt.Errorf("last refresh failed: %w", status.LastRefreshErr)
+	case status.RefreshCount == 0:
+		return errors.New("config has not been loaded yet")
+	case status.IsStale:
+		return fmt.Errorf("config is stale: last refreshed %s ago", status.StaleDuration.Round(time.Second))
+	}
+	return nil
+}
+
 // getDefaultClient returns the default client in a thread-safe manner.
 func getDefaultClient() *Client {
 	defaultClientMu.RLock()
@@ -234,6 +638,54 @@ func GetConfigInt(name string, defaultValue int) (int, error) {
 	return client.GetConfigInt(name, defaultValue)
 }
 
+func GetConfigBool(name string, defaultValue bool) (bool, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigBool(name, defaultValue)
+}
+
+// GetConfigBase64 retrieves base64-encoded bytes using the default client.
+// See Client.GetConfigBase64.
+func GetConfigBase64(name string) ([]byte, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return nil, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigBase64(name)
+}
+
+// GetConfigStringf retrieves a string under a formatted key using the
+// default client. See Client.GetConfigStringf.
+func GetConfigStringf(defaultValue string, format string, args ...interface{}) (string, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigStringf(defaultValue, format, args...)
+}
+
+// GetConfigIntf retrieves an int under a formatted key using the default
+// client. See Client.GetConfigIntf.
+func GetConfigIntf(defaultValue int, format string, args ...interface{}) (int, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigIntf(defaultValue, format, args...)
+}
+
+// GetConfigBoolf retrieves a bool under a formatted key using the default
+// client. See Client.GetConfigBoolf.
+func GetConfigBoolf(defaultValue bool, format string, args ...interface{}) (bool, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigBoolf(defaultValue, format, args...)
+}
+
 func GetConfigFloat(name string, defaultValue float64) (float64, error) {
 	client := getDefaultClient()
 	if client == nil {
@@ -242,6 +694,102 @@ func GetConfigFloat(name string, defaultValue float64) (float64, error) {
 	return client.GetConfigFloat(name, defaultValue)
 }
 
+func GetConfigEnum(name string, allowed []string, defaultValue string) (string, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigEnum(name, allowed, defaultValue)
+}
+
+// GetConfigURL retrieves an absolute URL using the default client. See
+// Client.GetConfigURL.
+func GetConfigURL(name string, allowedSchemes ...string) (*url.URL, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return nil, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigURL(name, allowedSchemes...)
+}
+
+// GetConfigInto overlays a configuration onto preset defaults using the
+// default client. See Client.GetConfigInto.
+func GetConfigInto(name string, data interface{}) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigInto(name, data)
+}
+
+// EvalBool evaluates a feature flag using the default client. See
+// Client.EvalBool.
+func EvalBool(name string, attributes map[string]interface{}) (bool, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return false, errors.New("no default client configured, call NewClient first")
+	}
+	return client.EvalBool(name, attributes)
+}
+
+func GetConfigSlice(name string, dest interface{}) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigSlice(name, dest)
+}
+
+// GetConfigBigInt retrieves an integer without precision loss using the
+// default client. See Client.GetConfigBigInt.
+func GetConfigBigInt(name string, defaultValue *big.Int) (*big.Int, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigBigInt(name, defaultValue)
+}
+
+// GetConfigBigFloat retrieves a number without precision loss using the
+// default client. See Client.GetConfigBigFloat.
+func GetConfigBigFloat(name string, defaultValue *big.Float) (*big.Float, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return defaultValue, errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigBigFloat(name, defaultValue)
+}
+
+// GetConfigFirst decodes the first of names present using the default
+// client. See Client.GetConfigFirst.
+func GetConfigFirst(dest interface{}, names ...string) (string, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return "", errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigFirst(dest, names...)
+}
+
+// GetConfigValidated retrieves the configuration with the given name using
+// the default client and validates it. See Client.GetConfigValidated.
+func GetConfigValidated(name string, data interface{}, validate func(interface{}) error) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigValidated(name, data, validate)
+}
+
+// GetConfigDecrypt retrieves and decrypts the configuration with the given
+// name using the default client. See Client.GetConfigDecrypt.
+func GetConfigDecrypt(name string, data interface{}) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errors.New("no default client configured, call NewClient first")
+	}
+	return client.GetConfigDecrypt(name, data)
+}
+
 // Close stops the background refresh goroutine of the Client by canceling
 // its associated context. This function allows graceful termination of the
 // background routine and prevents potential goroutine leaks. It should be
@@ -249,6 +797,7 @@ func GetConfigFloat(name string, defaultValue float64) (float64, error) {
 func (c *Client) Close() {
 	// Mark the client as closed using atomic operation for thread safety
 	c.closed.Store(true)
+	c.leaveGroup()
 	// Call the Cancel function associated with the Client's context.
 	// This cancels the context, causing the background refresh goroutine
 	// (started by NewClient) to return and terminate gracefully.
@@ -286,20 +835,17 @@ func (c *Client) GetConfig(name string, data interface{}, defaultValue interface
 		setDefaultValue(data, defaultValue)
 		return errors.New("client is closed")
 	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
-	if !ok {
+	marshal, err := c.marshalConfig(name)
+	if errors.Is(err, ErrConfigNotFound) {
 		setDefaultValue(data, defaultValue)
-		return errors.New("config not found")
+		return c.missingKeyErr()
 	}
-
-	marshal, err := yaml.Marshal(config)
 	if err != nil {
 		setDefaultValue(data, defaultValue)
 		return err
 	}
 	// Unmarshal the configuration data into the provided data pointer
-	err = yaml.Unmarshal(marshal, data)
+	err = c.unmarshal(marshal, data)
 	if err != nil {
 		setDefaultValue(data, defaultValue)
 		return err
@@ -308,15 +854,295 @@ func (c *Client) GetConfig(name string, data interface{}, defaultValue interface
 	return nil
 }
 
+// GetConfigInto overlays the configuration with the given name onto data,
+// which must be a non-nil pointer holding preset defaults. Only the keys
+// present in the config are written, so struct fields (including fields of
+// nested structs) and map entries the config doesn't mention keep their
+// defaults:
+//
+//	settings := Settings{Timeout: 30, Retries: 3}
+//	err := client.GetConfigInto("settings", &settings) // config "retries: 5" keeps Timeout 30
+//
+// The config is decoded into a copy of *data, which replaces *data only on
+// success; if the key is missing or decoding fails, *data is left as is.
+// Maps and pointers in the copy are shared with *data, so a failed decode
+// may still have written into them.
+func (c *Client) GetConfigInto(name string, data interface{}) error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	dataVal := reflect.ValueOf(data)
+	if dataVal.Kind() != reflect.Ptr || dataVal.IsNil() {
+		return errors.New("data must be a non-nil pointer")
+	}
+	marshal, err := c.marshalConfig(name)
+	if errors.Is(err, ErrConfigNotFound) {
+		return c.missingKeyErr()
+	}
+	if err != nil {
+		return err
+	}
+
+	overlay := reflect.New(dataVal.Elem().Type())
+	overlay.Elem().Set(dataVal.Elem())
+	if err := c.unmarshal(marshal, overlay.Interface()); err != nil {
+		return err
+	}
+	dataVal.Elem().Set(overlay.Elem())
+	return nil
+}
+
+// GetConfigFirst decodes the first of names present in the repository into
+// dest, like GetConfig, and returns the name that matched. Use it for
+// fallback keys, e.g. GetConfigFirst(&limits, "limits.tenant-a", "limits.default").
+// If none of the names is present it returns an empty name and the missing
+// key error (see ClientOptions.AllowMissingKeys), leaving dest unchanged.
+func (c *Client) GetConfigFirst(dest interface{}, names ...string) (string, error) {
+	if c.closed.Load() {
+		return "", errors.New("client is closed")
+	}
+	for _, name := range names {
+		marshal, err := c.marshalConfig(name)
+		if errors.Is(err, ErrConfigNotFound) {
+			continue
+		}
+		if err != nil {
+			return name, err
+		}
+		return name, c.unmarshal(marshal, dest)
+	}
+	return "", c.missingKeyErr()
+}
+
+// GetConfigValidated retrieves the configuration with the given name, decodes
+// it like GetConfig, and passes the decoded value (a pointer of the same type
+// as data) to validate. data is only written when validation succeeds, so a
+// rejected value never reaches the caller; the validator's error is returned
+// as is.
+func (c *Client) GetConfigValidated(name string, data interface{}, validate func(interface{}) error) error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	dataVal := reflect.ValueOf(data)
+	if dataVal.Kind() != reflect.Ptr || dataVal.IsNil() {
+		return errors.New("data must be a non-nil pointer")
+	}
+
+	marshal, err := c.marshalConfig(name)
+	if errors.Is(err, ErrConfigNotFound) {
+		return c.missingKeyErr()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Decode into a fresh value so a failed validation leaves data untouched
+	candidate := reflect.New(dataVal.Elem().Type())
+	if err := c.unmarshal(marshal, candidate.Interface()); err != nil {
+		return err
+	}
+	if err := validate(candidate.Interface()); err != nil {
+		return err
+	}
+	dataVal.Elem().Set(candidate.Elem())
+	return nil
+}
+
+// GetConfigDecrypt retrieves the configuration with the given name, which
+// must be a base64-encoded ciphertext string, decrypts it with the client's
+// Decryptor, and unmarshals the plaintext YAML into data.
+func (c *Client) GetConfigDecrypt(name string, data interface{}) error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	if c.decryptor == nil {
+		return errors.New("no decryptor configured")
+	}
+
+	config, ok := c.getData(name)
+	if !ok {
+		return c.missingKeyErr()
+	}
+	encoded, ok := config.(string)
+	if !ok {
+		return errors.New("config is not a string")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return fmt.Errorf("config is not valid base64: %w", err)
+	}
+	plaintext, err := c.decryptor(ciphertext)
+	if err != nil {
+		return fmt.Errorf("error decrypting config: %w", err)
+	}
+	return c.unmarshal(plaintext, data)
+}
+
+// base64Encodings are the encodings GetConfigBase64 tries, in order.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.URLEncoding,
+	base64.RawStdEncoding,
+	base64.RawURLEncoding,
+}
+
+// GetConfigBase64 retrieves the configuration with the given name, which
+// must be a base64-encoded string, and returns the decoded bytes, e.g. for
+// keys or certificates kept in YAML. The standard encoding is tried first,
+// then the URL-safe one, each with or without padding. Surrounding
+// whitespace is ignored.
+func (c *Client) GetConfigBase64(name string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return nil, c.missingKeyErr()
+	}
+	encoded, ok := config.(string)
+	if !ok {
+		return nil, errors.New("config is not a string")
+	}
+	encoded = strings.TrimSpace(encoded)
+	var err error
+	for _, encoding := range base64Encodings {
+		var decoded []byte
+		if decoded, err = encoding.DecodeString(encoded); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("config is not valid base64: %w", err)
+}
+
+// unmarshal decodes YAML into out, rejecting unknown struct fields if the
+// client is strict.
+func (c *Client) unmarshal(in []byte, out interface{}) error {
+	if !c.strict {
+		return yaml.Unmarshal(in, out)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(in))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// getData looks up a configuration value in the repository, performing the
+// deferred initial refresh first for lazy clients. Missing keys are looked up
+// again once the first refresh succeeds if it does within WaitForLoad.
+func (c *Client) getData(name string) (interface{}, bool) {
+	c.ensureLoaded()
+	config, ok := c.lookup(c.activeRepository(), name)
+	if !ok && c.awaitFirstLoad() {
+		config, ok = c.lookup(c.activeRepository(), name)
+	}
+	return config, ok
+}
+
+// awaitFirstLoad waits up to waitForLoad for the first successful refresh and
+// reports whether it happened during the wait. It returns false immediately
+// if waiting is disabled or the client has already loaded.
+func (c *Client) awaitFirstLoad() bool {
+	if c.waitForLoad <= 0 || c.loaded == nil {
+		return false
+	}
+	select {
+	case <-c.loaded:
+		return false
+	default:
+	}
+	timer := time.NewTimer(c.waitForLoad)
+	defer timer.Stop()
+	select {
+	case <-c.loaded:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// ensureLoaded performs the deferred initial refresh of a lazy client once.
+func (c *Client) ensureLoaded() {
+	if !c.lazy {
+		return
+	}
+	c.lazyOnce.Do(func() {
+		// Skip if the background refresh already loaded data
+		if c.GetRefreshStatus().RefreshCount == 0 {
+			_ = c.refreshShared()
+		}
+	})
+}
+
+// missingKeyErr returns the error reported for a missing key according to
+// the client's AllowMissingKeys policy.
+func (c *Client) missingKeyErr() error {
+	if c.allowMissingKeys {
+		return nil
+	}
+	return ErrConfigNotFound
+}
+
+// marshalConfig returns the YAML encoding of the named configuration value,
+// reusing the cached encoding while the repository content is unchanged.
+func (c *Client) marshalConfig(name string) ([]byte, error) {
+	cached, hash, ok := c.cache.get(name)
+	if ok {
+		return cached, nil
+	}
+
+	// Get the configuration data from the repository
+	config, ok := c.getData(name)
+	if !ok {
+		return nil, ErrConfigNotFound
+	}
+	marshal, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(name, hash, marshal)
+	return marshal, nil
+}
+
+// GetConfigSlice retrieves the sequence configuration with the given name from
+// the repository and unmarshals it into dest, which must be a non-nil pointer
+// to a slice (e.g. *[]Rule). It returns an error if the configuration is not a
+// sequence or its elements cannot be unmarshaled into the slice element type.
+func (c *Client) GetConfigSlice(name string, dest interface{}) error {
+	if c.closed.Load() {
+		return errors.New("client is closed")
+	}
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() || destVal.Elem().Kind() != reflect.Slice {
+		return errors.New("dest must be a non-nil pointer to a slice")
+	}
+
+	// Get the configuration data from the repository
+	config, ok := c.getData(name)
+	if !ok {
+		return c.missingKeyErr()
+	}
+	if _, ok := config.([]interface{}); !ok {
+		return errors.New("config is not a sequence")
+	}
+
+	marshal, err := c.marshalConfig(name)
+	if err != nil {
+		return err
+	}
+	return c.unmarshal(marshal, dest)
+}
+
 // GetConfigArrayOfStrings retrieves the configuration with the given name from the repository
 func (c *Client) GetConfigArrayOfStrings(name string, defaultValue []string) ([]string, error) {
 	if c.closed.Load() {
 		return defaultValue, errors.New("client is closed")
 	}
 	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
+	config, ok := c.getData(name)
 	if !ok {
-		return defaultValue, errors.New("config not found")
+		return defaultValue, c.missingKeyErr()
 	}
 
 	configArray, ok := config.([]interface{})
@@ -341,9 +1167,9 @@ func (c *Client) GetConfigString(name string, defaultValue string) (string, erro
 		return defaultValue, errors.New("client is closed")
 	}
 	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
+	config, ok := c.getData(name)
 	if !ok {
-		return defaultValue, errors.New("config not found")
+		return defaultValue, c.missingKeyErr()
 	}
 
 	configString, ok := config.(string)
@@ -360,9 +1186,9 @@ func (c *Client) GetConfigInt(name string, defaultValue int) (int, error) {
 		return defaultValue, errors.New("client is closed")
 	}
 	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
+	config, ok := c.getData(name)
 	if !ok {
-		return defaultValue, errors.New("config not found")
+		return defaultValue, c.missingKeyErr()
 	}
 	configInt, ok := config.(int)
 	if !ok {
@@ -378,9 +1204,9 @@ func (c *Client) GetConfigFloat(name string, defaultValue float64) (float64, err
 		return defaultValue, errors.New("client is closed")
 	}
 	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
+	config, ok := c.getData(name)
 	if !ok {
-		return defaultValue, errors.New("config not found")
+		return defaultValue, c.missingKeyErr()
 	}
 	configInt, ok := config.(float64)
 	if !ok {
@@ -389,3 +1215,198 @@ func (c *Client) GetConfigFloat(name string, defaultValue float64) (float64, err
 
 	return configInt, nil
 }
+
+// GetConfigBool retrieves the configuration with the given name from the repository
+func (c *Client) GetConfigBool(name string, defaultValue bool) (bool, error) {
+	if c.closed.Load() {
+		return defaultValue, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultValue, c.missingKeyErr()
+	}
+	configBool, ok := config.(bool)
+	if !ok {
+		return defaultValue, errors.New("config is not a bool")
+	}
+
+	return configBool, nil
+}
+
+// GetConfigStringf is GetConfigString for the key fmt.Sprintf(format, args...),
+// for key names computed at the call site, e.g.
+//
+//	limit, err := configClient.GetConfigStringf("none", "limits.%s.max", tenant)
+func (c *Client) GetConfigStringf(defaultValue string, format string, args ...interface{}) (string, error) {
+	return c.GetConfigString(fmt.Sprintf(format, args...), defaultValue)
+}
+
+// GetConfigIntf is GetConfigInt for the key fmt.Sprintf(format, args...).
+func (c *Client) GetConfigIntf(defaultValue int, format string, args ...interface{}) (int, error) {
+	return c.GetConfigInt(fmt.Sprintf(format, args...), defaultValue)
+}
+
+// GetConfigBoolf is GetConfigBool for the key fmt.Sprintf(format, args...).
+func (c *Client) GetConfigBoolf(defaultValue bool, format string, args ...interface{}) (bool, error) {
+	return c.GetConfigBool(fmt.Sprintf(format, args...), defaultValue)
+}
+
+// GetConfigBigInt retrieves an integer configuration value without precision
+// loss. It accepts integers of any size when the repository sets
+// PreserveBigNumbers, and falls back to the decoded int, uint64 or integral
+// float64 otherwise.
+func (c *Client) GetConfigBigInt(name string, defaultValue *big.Int) (*big.Int, error) {
+	if c.closed.Load() {
+		return defaultValue, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultValue, c.missingKeyErr()
+	}
+	switch v := config.(type) {
+	case int:
+		return big.NewInt(int64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		if n, accuracy := big.NewFloat(v).Int(nil); accuracy == big.Exact {
+			return n, nil
+		}
+	case string:
+		// Decimal only, as PreserveBigNumbers stores them: an ID such as
+		// "0123" must not be read as octal
+		if n, ok := new(big.Int).SetString(v, 10); ok {
+			return n, nil
+		}
+	}
+	return defaultValue, errors.New("config is not an integer")
+}
+
+// GetConfigBigFloat retrieves a numeric configuration value without
+// precision loss. Decimals with more significant digits than a float64 holds
+// are exact when the repository sets PreserveBigNumbers; other values are
+// converted from their decoded int, uint64 or float64.
+func (c *Client) GetConfigBigFloat(name string, defaultValue *big.Float) (*big.Float, error) {
+	if c.closed.Load() {
+		return defaultValue, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultValue, c.missingKeyErr()
+	}
+	switch v := config.(type) {
+	case int:
+		return new(big.Float).SetInt64(int64(v)), nil
+	case uint64:
+		return new(big.Float).SetUint64(v), nil
+	case float64:
+		return big.NewFloat(v), nil
+	case string:
+		// About 3.3 bits per decimal digit keeps every digit exact
+		prec := uint(len(v))*4 + 64
+		if f, _, err := big.ParseFloat(v, 10, prec, big.ToNearestEven); err == nil {
+			return f, nil
+		}
+	}
+	return defaultValue, errors.New("config is not a number")
+}
+
+// Keys returns the sorted top-level configuration names currently loaded, or
+// nil if the client is closed.
+func (c *Client) Keys() []string {
+	if c.closed.Load() {
+		return nil
+	}
+	c.ensureLoaded()
+	keys := source.Keys(c.activeRepository())
+	if keys == nil {
+		keys = make([]string, 0)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TypeOf returns the Go type the configuration with the given name was decoded
+// as (e.g. "int", "string", "[]interface {}"), which helps diagnose why a typed
+// getter such as GetConfigInt rejects a value. The boolean is false if the
+// configuration is missing or the client is closed.
+func (c *Client) TypeOf(name string) (string, bool) {
+	if c.closed.Load() {
+		return "", false
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%T", config), true
+}
+
+// GetConfigEnum retrieves the string configuration with the given name from the
+// repository and checks that it is one of the allowed values. The default value
+// is returned when the configuration is missing, is not a string, or is not in
+// the allowed set.
+func (c *Client) GetConfigEnum(name string, allowed []string, defaultValue string) (string, error) {
+	return c.getConfigEnum(name, allowed, defaultValue, false)
+}
+
+// GetConfigEnumFold is like GetConfigEnum but compares against the allowed
+// values case-insensitively. The matching entry from allowed is returned, so
+// callers always see the canonical spelling.
+func (c *Client) GetConfigEnumFold(name string, allowed []string, defaultValue string) (string, error) {
+	return c.getConfigEnum(name, allowed, defaultValue, true)
+}
+
+func (c *Client) getConfigEnum(name string, allowed []string, defaultValue string, fold bool) (string, error) {
+	if c.closed.Load() {
+		return defaultValue, errors.New("client is closed")
+	}
+	// Get the configuration data from the repository
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultValue, c.missingKeyErr()
+	}
+	value, ok := config.(string)
+	if !ok {
+		return defaultValue, errors.New("config is not a string")
+	}
+	for _, a := range allowed {
+		if a == value || (fold && strings.EqualFold(a, value)) {
+			return a, nil
+		}
+	}
+	return defaultValue, fmt.Errorf("config value %q is not one of %v", value, allowed)
+}
+
+// GetConfigURL retrieves a string configuration value and parses it as an
+// absolute URL, e.g. a webhook endpoint. Malformed values and URLs without a
+// scheme or host (such as "/hooks" or "example.com/hooks") are an error, so
+// a bad value is caught when it is read rather than when it is first used.
+// When allowedSchemes are given, the URL's scheme must be one of them,
+// compared case-insensitively. A missing key yields a nil URL with the
+// usual missing key error.
+func (c *Client) GetConfigURL(name string, allowedSchemes ...string) (*url.URL, error) {
+	if c.closed.Load() {
+		return nil, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return nil, c.missingKeyErr()
+	}
+	value, ok := config.(string)
+	if !ok {
+		return nil, errors.New("config is not a string")
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("config is not a valid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("config value %q is not an absolute URL", value)
+	}
+	if len(allowedSchemes) > 0 && !slices.ContainsFunc(allowedSchemes, func(s string) bool {
+		return strings.EqualFold(s, u.Scheme)
+	}) {
+		return nil, fmt.Errorf("config URL scheme %q is not one of %v", u.Scheme, allowedSchemes)
+	}
+	return u, nil
+}
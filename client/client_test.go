@@ -3,12 +3,22 @@ package client
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,7 +29,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/fullstorydev/emulators/storage/gcsemu"
 
+	"github.com/sardine-ai/go-remote-config/schedule"
 	"github.com/sardine-ai/go-remote-config/source"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewClient(t *testing.T) {
@@ -234,6 +248,10 @@ func (t *test) GetData(_ string) (config interface{}, isPresent bool) {
 	return t.GetRefeshCount, true
 }
 
+func (t *test) GetAllData() map[string]interface{} {
+	return map[string]interface{}{"test": t.GetRefeshCount}
+}
+
 func (t *test) GetRawData() []byte {
 	return []byte("test")
 }
@@ -250,6 +268,10 @@ func (t *test) GetName() string {
 	return "test"
 }
 
+func (t *test) Type() string {
+	return "test"
+}
+
 func TestRefresh(t *testing.T) {
 	// should throw Err
 	_, err := NewClient(context.Background(), &test{ShouldError: true}, 1*time.Second)
@@ -268,7 +290,8 @@ func TestRefresh(t *testing.T) {
 	if count != 0 {
 		t.Errorf("Expected count to be 0, got %d", count)
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
 	refresh(ctx, client)
 	if client.GetConfig("test", &count, nil) != nil {
 		t.Errorf("Expected error, got nil")
@@ -300,6 +323,10 @@ func (m *mockRepository) GetName() string {
 	return "mock"
 }
 
+func (m *mockRepository) Type() string {
+	return "mock"
+}
+
 func (m *mockRepository) GetData(key string) (interface{}, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -307,10 +334,23 @@ func (m *mockRepository) GetData(key string) (interface{}, bool) {
 	return v, ok
 }
 
+func (m *mockRepository) GetAllData() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.data)
+}
+
 func (m *mockRepository) GetRawData() []byte {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return []byte("name: test\nage: 30")
+	raw, _ := yaml.Marshal(m.data)
+	return raw
+}
+
+func (m *mockRepository) setData(key string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
 }
 
 func (m *mockRepository) Refresh() error {
@@ -840,3 +880,2025 @@ func BenchmarkClientGetConfigString(b *testing.B) {
 		_, _ = client.GetConfigString("name", "default")
 	}
 }
+
+// TestRefreshNowCoalescesConcurrentCalls tests that concurrent RefreshNow calls
+// share a single underlying Refresh.
+func TestRefreshNowCoalescesConcurrentCalls(t *testing.T) {
+	repo := newMockRepository()
+	ctx := context.Background()
+	client, err := NewClientWithOptions(ctx, repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	initialCount := repo.getRefreshCount()
+	repo.refreshDelay = 100 * time.Millisecond
+
+	var wg sync.WaitGroup
+	const numGoroutines = 20
+	errs := make(chan error, numGoroutines)
+	start := make(chan struct{})
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- client.RefreshNow(ctx)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected no error from RefreshNow, got: %v", err)
+		}
+	}
+	if got := repo.getRefreshCount() - initialCount; got != 1 {
+		t.Errorf("Expected exactly 1 underlying refresh, got %d", got)
+	}
+}
+
+// TestRefreshNowContextCanceled tests that RefreshNow returns when ctx is done
+func TestRefreshNowContextCanceled(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	repo.refreshDelay = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := client.RefreshNow(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+// TestGetConfigEnum tests enum retrieval with valid, invalid, and missing values
+func TestGetConfigEnum(t *testing.T) {
+	repo := newMockRepository()
+	repo.data["mode"] = "fast"
+	repo.data["level"] = "WARN"
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	modes := []string{"fast", "safe"}
+	levels := []string{"debug", "info", "warn", "error"}
+
+	tests := []struct {
+		name    string
+		key     string
+		allowed []string
+		fold    bool
+		want    string
+		wantErr bool
+	}{
+		{"valid", "mode", modes, false, "fast", false},
+		{"invalid", "level", levels, false, "info", true},
+		{"missing", "missing", modes, false, "info", true},
+		{"not a string", "age", modes, false, "info", true},
+		{"case-insensitive", "level", levels, true, "warn", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			var err error
+			if tt.fold {
+				got, err = client.GetConfigEnumFold(tt.key, tt.allowed, "info")
+			} else {
+				got, err = client.GetConfigEnum(tt.key, tt.allowed, "info")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+// mockWatchRepository is a mock repository that reports changes on demand
+type mockWatchRepository struct {
+	*mockRepository
+	changes chan struct{}
+}
+
+func (m *mockWatchRepository) WaitForChange(ctx context.Context) (bool, error) {
+	select {
+	case <-m.changes:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// TestClientWatchRefreshesOnChange tests that a reported change triggers an immediate refresh
+func TestClientWatchRefreshesOnChange(t *testing.T) {
+	repo := &mockWatchRepository{mockRepository: newMockRepository(), changes: make(chan struct{})}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Watch: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if repo.getRefreshCount() != 1 {
+		t.Fatalf("Expected 1 refresh, got %d", repo.getRefreshCount())
+	}
+
+	repo.changes <- struct{}{}
+
+	deadline := time.Now().Add(time.Second)
+	for repo.getRefreshCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if repo.getRefreshCount() != 2 {
+		t.Errorf("Expected 2 refreshes after change, got %d", repo.getRefreshCount())
+	}
+}
+
+// alwaysChangedRepository is a watcher that reports a change on every call
+type alwaysChangedRepository struct {
+	*mockRepository
+}
+
+func (m *alwaysChangedRepository) WaitForChange(ctx context.Context) (bool, error) {
+	return true, ctx.Err()
+}
+
+// TestClientWatchBacksOffWithoutChange tests that a reported change the refresh does not see does not refresh in a tight loop
+func TestClientWatchBacksOffWithoutChange(t *testing.T) {
+	repo := &alwaysChangedRepository{mockRepository: newMockRepository()}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Watch: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(200 * time.Millisecond)
+	if count := repo.getRefreshCount(); count > 2 {
+		t.Errorf("Expected the watch loop to back off after an unchanged refresh, got %d refreshes", count)
+	}
+}
+
+// TestGetConfigCacheInvalidatedOnChange tests that cached values are dropped after a content change
+func TestGetConfigCacheInvalidatedOnChange(t *testing.T) {
+	type Limits struct {
+		Max int `yaml:"max"`
+	}
+	repo := newMockRepository()
+	repo.data["limits"] = map[string]interface{}{"max": 10}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	var limits Limits
+	if err := client.GetConfig("limits", &limits, nil); err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if _, _, ok := client.cache.get("limits"); !ok {
+		t.Error("Expected limits to be cached after first read")
+	}
+
+	repo.setData("limits", map[string]interface{}{"max": 20})
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if _, _, ok := client.cache.get("limits"); ok {
+		t.Error("Expected cache to be invalidated after content change")
+	}
+
+	if err := client.GetConfig("limits", &limits, nil); err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if limits.Max != 20 {
+		t.Errorf("Expected max 20 after refresh, got %d", limits.Max)
+	}
+}
+
+// BenchmarkClientGetConfigStruct benchmarks repeated GetConfig calls for an
+// unchanged key, which are served from the marshal cache.
+func BenchmarkClientGetConfigStruct(b *testing.B) {
+	type Address struct {
+		Street string `yaml:"street"`
+		City   string `yaml:"city"`
+	}
+	repo := newMockRepository()
+	repo.data["address"] = map[string]interface{}{"street": "123 Main St", "city": "New York"}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var address Address
+			_ = client.GetConfig("address", &address, nil)
+		}
+	})
+
+	// Baseline: the marshal/unmarshal round trip GetConfig performs without the cache
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var address Address
+			value, _ := repo.GetData("address")
+			raw, _ := yaml.Marshal(value)
+			_ = yaml.Unmarshal(raw, &address)
+		}
+	})
+}
+
+// TestMissingKeyPolicy tests missing-key handling with and without AllowMissingKeys
+func TestMissingKeyPolicy(t *testing.T) {
+	for _, allowMissing := range []bool{false, true} {
+		repo := newMockRepository()
+		client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{AllowMissingKeys: allowMissing})
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		checkErr := func(getter string, err error) {
+			t.Helper()
+			if allowMissing && err != nil {
+				t.Errorf("AllowMissingKeys=true: %s: Expected nil error, got %v", getter, err)
+			}
+			if !allowMissing && !errors.Is(err, ErrConfigNotFound) {
+				t.Errorf("AllowMissingKeys=false: %s: Expected ErrConfigNotFound, got %v", getter, err)
+			}
+		}
+
+		var data string
+		err = client.GetConfig("missing", &data, "fallback")
+		checkErr("GetConfig", err)
+		if data != "fallback" {
+			t.Errorf("GetConfig: Expected default 'fallback', got '%s'", data)
+		}
+
+		str, err := client.GetConfigString("missing", "")
+		checkErr("GetConfigString", err)
+		if str != "" {
+			t.Errorf("GetConfigString: Expected zero value, got '%s'", str)
+		}
+
+		i, err := client.GetConfigInt("missing", 0)
+		checkErr("GetConfigInt", err)
+		if i != 0 {
+			t.Errorf("GetConfigInt: Expected zero value, got %d", i)
+		}
+
+		f, err := client.GetConfigFloat("missing", 0)
+		checkErr("GetConfigFloat", err)
+		if f != 0 {
+			t.Errorf("GetConfigFloat: Expected zero value, got %f", f)
+		}
+
+		arr, err := client.GetConfigArrayOfStrings("missing", nil)
+		checkErr("GetConfigArrayOfStrings", err)
+		if arr != nil {
+			t.Errorf("GetConfigArrayOfStrings: Expected nil, got %v", arr)
+		}
+
+		enum, err := client.GetConfigEnum("missing", []string{"a", "b"}, "c")
+		checkErr("GetConfigEnum", err)
+		if enum != "c" {
+			t.Errorf("GetConfigEnum: Expected default 'c', got '%s'", enum)
+		}
+
+		// Type mismatches are errors in both modes
+		if _, err := client.GetConfigInt("name", 0); err == nil {
+			t.Errorf("AllowMissingKeys=%v: Expected type mismatch error", allowMissing)
+		}
+
+		client.Close()
+	}
+}
+
+// TestGetConfigSlice tests unmarshaling a sequence of maps into a slice of structs
+func TestGetConfigSlice(t *testing.T) {
+	type Rule struct {
+		Name   string `yaml:"name"`
+		Action string `yaml:"action"`
+	}
+	repo := newMockRepository()
+	repo.data["rules"] = []interface{}{
+		map[string]interface{}{"name": "block-tor", "action": "deny"},
+		map[string]interface{}{"name": "allow-internal", "action": "allow"},
+	}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	var rules []Rule
+	if err := client.GetConfigSlice("rules", &rules); err != nil {
+		t.Fatalf("Failed to get rules: %v", err)
+	}
+	want := []Rule{{"block-tor", "deny"}, {"allow-internal", "allow"}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("Expected %v, got %v", want, rules)
+	}
+
+	if err := client.GetConfigSlice("name", &rules); err == nil {
+		t.Error("Expected error for non-sequence config")
+	}
+	var notSlice Rule
+	if err := client.GetConfigSlice("rules", &notSlice); err == nil {
+		t.Error("Expected error for non-slice destination")
+	}
+	if err := client.GetConfigSlice("missing", &rules); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
+
+// TestLazyClientDefersInitialRefresh tests that lazy clients refresh on first read only
+func TestLazyClientDefersInitialRefresh(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Lazy: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if count := repo.getRefreshCount(); count != 0 {
+		t.Errorf("Expected no refresh before first read, got %d", count)
+	}
+
+	name, err := client.GetConfigString("name", "")
+	if err != nil {
+		t.Fatalf("Failed to get name: %v", err)
+	}
+	if name != "test" {
+		t.Errorf("Expected 'test', got '%s'", name)
+	}
+	if count := repo.getRefreshCount(); count != 1 {
+		t.Errorf("Expected 1 refresh after first read, got %d", count)
+	}
+
+	var age int
+	_ = client.GetConfig("age", &age, nil)
+	_, _ = client.GetConfigInt("age", 0)
+	if count := repo.getRefreshCount(); count != 1 {
+		t.Errorf("Expected no additional refresh on later reads, got %d", count)
+	}
+}
+
+// TestLazyClientStillRefreshesPeriodically tests that the ticker runs before any read
+func TestLazyClientStillRefreshesPeriodically(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 20*time.Millisecond, ClientOptions{Lazy: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if count := repo.getRefreshCount(); count == 0 {
+		t.Error("Expected periodic refresh to run in lazy mode")
+	}
+}
+
+// replaceData atomically replaces the mock repository's data map
+func (m *mockRepository) replaceData(data map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = data
+}
+
+// TestSnapshotConsistentDuringRefresh tests that a snapshot never mixes old and new config
+func TestSnapshotConsistentDuringRefresh(t *testing.T) {
+	repo := newMockRepository()
+	repo.replaceData(map[string]interface{}{"a": "v0", "b": "v0"})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for i := 1; ctx.Err() == nil; i++ {
+			version := "v" + strconv.Itoa(i)
+			repo.replaceData(map[string]interface{}{"a": version, "b": version})
+			_ = client.RefreshNow(ctx)
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		snapshot, err := client.Snapshot()
+		if err != nil {
+			t.Fatalf("Failed to take snapshot: %v", err)
+		}
+		a, _ := snapshot.GetConfigString("a", "")
+		time.Sleep(10 * time.Microsecond)
+		b, _ := snapshot.GetConfigString("b", "")
+		if a != b {
+			t.Fatalf("Snapshot is inconsistent: a=%s b=%s", a, b)
+		}
+	}
+}
+
+// TestSnapshotIsFrozen tests that a snapshot does not change after a refresh
+func TestSnapshotIsFrozen(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	snapshot, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	repo.setData("name", "changed")
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+
+	if name, _ := snapshot.GetConfigString("name", ""); name != "test" {
+		t.Errorf("Expected snapshot to keep 'test', got '%s'", name)
+	}
+	if name, _ := client.GetConfigString("name", ""); name != "changed" {
+		t.Errorf("Expected client to see 'changed', got '%s'", name)
+	}
+	if _, err := snapshot.GetConfigString("missing", ""); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound from snapshot, got %v", err)
+	}
+
+	client.Close()
+	if _, err := client.Snapshot(); err == nil {
+		t.Error("Expected error taking a snapshot of a closed client")
+	}
+}
+
+// TestGetConfigMergeKeys tests that keys pulled in through a YAML merge key are readable
+func TestGetConfigMergeKeys(t *testing.T) {
+	type Service struct {
+		Timeout int `yaml:"timeout"`
+		Retries int `yaml:"retries"`
+	}
+	path := filepath.Join(t.TempDir(), "merge.yaml")
+	content := "defaults: &defaults\n  timeout: 30\n  retries: 3\nservice:\n  <<: *defaults\n  retries: 5\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	repo := &source.FileRepository{Name: "merge", Path: path, DecodeOptions: source.DecodeOptions{VerifyMergeKeys: true}}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	var service Service
+	if err := client.GetConfig("service", &service, Service{}); err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if service.Timeout != 30 || service.Retries != 5 {
+		t.Errorf("Expected {30 5}, got %+v", service)
+	}
+}
+
+// TestClientScheduler tests that a custom scheduler controls refresh timing
+func TestClientScheduler(t *testing.T) {
+	var fired atomic.Int32
+	scheduler := schedule.SchedulerFunc(func(now time.Time) time.Time {
+		if fired.Add(1) > 2 {
+			return time.Time{}
+		}
+		return now.Add(10 * time.Millisecond)
+	})
+
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Scheduler: scheduler})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(200 * time.Millisecond)
+
+	// One initial refresh plus the two scheduled ones
+	if count := client.GetRefreshStatus().RefreshCount; count != 3 {
+		t.Errorf("Expected refresh count 3, got %d", count)
+	}
+}
+
+// TestClientTypeOf tests the reported types of decoded YAML values
+func TestClientTypeOf(t *testing.T) {
+	var data map[string]interface{}
+	content := `
+int: 42
+float: 3.14
+string: hello
+quoted_number: "42"
+bool: true
+null_value: null
+timestamp: 2024-01-15T10:00:00Z
+list: [1, 2]
+map: {a: 1}
+`
+	if err := yaml.Unmarshal([]byte(content), &data); err != nil {
+		t.Fatalf("Failed to decode test data: %v", err)
+	}
+	repo := newMockRepository()
+	repo.replaceData(data)
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	tests := map[string]string{
+		"int":           "int",
+		"float":         "float64",
+		"string":        "string",
+		"quoted_number": "string",
+		"bool":          "bool",
+		"null_value":    "<nil>",
+		"timestamp":     "time.Time",
+		"list":          "[]interface {}",
+		"map":           "map[string]interface {}",
+	}
+	for name, want := range tests {
+		got, ok := client.TypeOf(name)
+		if !ok {
+			t.Errorf("Expected %q to be present", name)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected type of %q to be %q, got %q", name, want, got)
+		}
+	}
+
+	if _, ok := client.TypeOf("missing"); ok {
+		t.Error("Expected missing key to report not present")
+	}
+}
+
+// TestClientKeys tests that Keys returns the loaded top-level names in sorted order
+func TestClientKeys(t *testing.T) {
+	repo := newMockRepository()
+	repo.replaceData(map[string]interface{}{
+		"zeta":           1,
+		"alpha":          2,
+		"feature_flag_b": true,
+		"feature_flag_a": false,
+		"nested":         map[string]interface{}{"inner": 1},
+	})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	want := []string{"alpha", "feature_flag_a", "feature_flag_b", "nested", "zeta"}
+	for i := 0; i < 5; i++ {
+		if got := client.Keys(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+
+	client.Close()
+	if keys := client.Keys(); keys != nil {
+		t.Errorf("Expected nil keys after close, got %v", keys)
+	}
+}
+
+// keyListingRepository is a repository whose Keys must be used instead of copying its data
+type keyListingRepository struct {
+	*mockRepository
+	t *testing.T
+}
+
+func (k *keyListingRepository) Keys() []string {
+	return []string{"b", "a"}
+}
+
+func (k *keyListingRepository) GetAllData() map[string]interface{} {
+	k.t.Error("Expected Keys not to copy the data")
+	return k.mockRepository.GetAllData()
+}
+
+// TestClientKeysWithoutCopy tests that Keys lists the repository's keys without copying its data
+func TestClientKeysWithoutCopy(t *testing.T) {
+	repo := &keyListingRepository{mockRepository: newMockRepository(), t: t}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if got := client.Keys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Expected the repository's keys sorted, got %v", got)
+	}
+}
+
+// TestGetConfigValidated tests passing and failing validators
+func TestGetConfigValidated(t *testing.T) {
+	type Server struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	validPort := func(v interface{}) error {
+		if port := v.(*Server).Port; port < 1 || port > 65535 {
+			return fmt.Errorf("port %d out of range", port)
+		}
+		return nil
+	}
+
+	repo := newMockRepository()
+	repo.setData("good", map[string]interface{}{"host": "localhost", "port": 8080})
+	repo.setData("bad", map[string]interface{}{"host": "localhost", "port": 70000})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	var server Server
+	if err := client.GetConfigValidated("good", &server, validPort); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if server != (Server{"localhost", 8080}) {
+		t.Errorf("Expected {localhost 8080}, got %+v", server)
+	}
+
+	previous := server
+	err = client.GetConfigValidated("bad", &server, validPort)
+	if err == nil || err.Error() != "port 70000 out of range" {
+		t.Errorf("Expected validator error, got: %v", err)
+	}
+	if server != previous {
+		t.Errorf("Expected data to be untouched after failed validation, got %+v", server)
+	}
+
+	// The rejected value is not cached in a mutated form
+	var raw map[string]interface{}
+	if err := client.GetConfig("bad", &raw, nil); err != nil || raw["port"] != 70000 {
+		t.Errorf("Expected stored value to be unchanged, got %v (err %v)", raw, err)
+	}
+
+	if err := client.GetConfigValidated("missing", &server, validPort); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+	if err := client.GetConfigValidated("good", server, validPort); err == nil {
+		t.Error("Expected error for non-pointer data")
+	}
+}
+
+// xorDecryptor is a trivial symmetric cipher for testing GetConfigDecrypt
+func xorDecryptor(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0x5a
+	}
+	return out, nil
+}
+
+// TestGetConfigDecrypt tests decrypting base64 ciphertext values
+func TestGetConfigDecrypt(t *testing.T) {
+	type Credentials struct {
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+	}
+	encrypt := func(plaintext string) string {
+		ciphertext, _ := xorDecryptor([]byte(plaintext))
+		return base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	repo := newMockRepository()
+	repo.setData("db_credentials", encrypt("user: admin\npassword: hunter2\n"))
+	repo.setData("api_token", encrypt("s3cr3t"))
+	repo.setData("not_base64", "!!!")
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Decryptor: xorDecryptor})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	var creds Credentials
+	if err := client.GetConfigDecrypt("db_credentials", &creds); err != nil {
+		t.Fatalf("Failed to decrypt credentials: %v", err)
+	}
+	if creds != (Credentials{"admin", "hunter2"}) {
+		t.Errorf("Expected decrypted credentials, got %+v", creds)
+	}
+
+	var token string
+	if err := client.GetConfigDecrypt("api_token", &token); err != nil || token != "s3cr3t" {
+		t.Errorf("Expected token 's3cr3t', got '%s' (err %v)", token, err)
+	}
+
+	if err := client.GetConfigDecrypt("not_base64", &token); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+	if err := client.GetConfigDecrypt("age", &token); err == nil {
+		t.Error("Expected error for non-string value")
+	}
+	if err := client.GetConfigDecrypt("missing", &token); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+
+	failing, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{
+		Decryptor: func([]byte) ([]byte, error) { return nil, errors.New("kms unavailable") },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer failing.Close()
+	if err := failing.GetConfigDecrypt("api_token", &token); err == nil || !strings.Contains(err.Error(), "kms unavailable") {
+		t.Errorf("Expected decryptor error, got %v", err)
+	}
+
+	plain, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer plain.Close()
+	if err := plain.GetConfigDecrypt("api_token", &token); err == nil {
+		t.Error("Expected error without a decryptor")
+	}
+}
+
+// TestClientBootstrap tests that bootstrap values are served while the remote
+// fails and replaced once a refresh succeeds
+func TestClientBootstrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bootstrap.yaml")
+	if err := os.WriteFile(path, []byte("name: bootstrap\nregion: us-east-1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write bootstrap file: %v", err)
+	}
+	repo := newMockRepository()
+	repo.setError(true)
+
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{BootstrapPath: path})
+	if err != nil {
+		t.Fatalf("Expected bootstrapped client, got error: %v", err)
+	}
+	defer client.Close()
+
+	if name, err := client.GetConfigString("name", ""); err != nil || name != "bootstrap" {
+		t.Errorf("Expected bootstrap name, got %q (err: %v)", name, err)
+	}
+	status := client.GetRefreshStatus()
+	if !status.Bootstrapped || status.LastRefreshErr == nil {
+		t.Errorf("Expected bootstrapped status with refresh error, got %+v", status)
+	}
+
+	repo.setError(false)
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected refresh to succeed, got: %v", err)
+	}
+	if name, _ := client.GetConfigString("name", ""); name != "test" {
+		t.Errorf("Expected remote name after refresh, got %q", name)
+	}
+	if _, err := client.GetConfigString("region", ""); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected bootstrap-only key to be gone, got: %v", err)
+	}
+	if client.GetRefreshStatus().Bootstrapped {
+		t.Error("Expected client to stop serving bootstrap data")
+	}
+}
+
+// TestClientBootstrapRemoteSucceeds tests that a reachable remote overrides the bootstrap file
+func TestClientBootstrapRemoteSucceeds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bootstrap.yaml")
+	if err := os.WriteFile(path, []byte("name: bootstrap\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write bootstrap file: %v", err)
+	}
+	client, err := NewClientWithOptions(context.Background(), newMockRepository(), 1*time.Hour, ClientOptions{BootstrapPath: path})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if name, _ := client.GetConfigString("name", ""); name != "test" {
+		t.Errorf("Expected remote name, got %q", name)
+	}
+}
+
+// TestClientBootstrapMissingFile tests that an unreadable bootstrap file does not mask remote errors
+func TestClientBootstrapMissingFile(t *testing.T) {
+	repo := newMockRepository()
+	repo.setError(true)
+	_, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{BootstrapPath: filepath.Join(t.TempDir(), "missing.yaml")})
+	if err == nil {
+		t.Error("Expected error when both bootstrap and remote fail")
+	}
+}
+
+// TestConfigRef tests that a ConfigRef serves the cached value and updates after a refresh
+func TestConfigRef(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ref, err := GetConfigRef[string](client, "name")
+	if err != nil {
+		t.Fatalf("Failed to get ref: %v", err)
+	}
+	if got := ref.Value(); got != "test" {
+		t.Errorf("Expected 'test', got '%s'", got)
+	}
+
+	repo.setData("name", "updated")
+	if got := ref.Value(); got != "test" {
+		t.Errorf("Expected cached 'test' before refresh, got '%s'", got)
+	}
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if got := ref.Value(); got != "updated" {
+		t.Errorf("Expected 'updated' after refresh, got '%s'", got)
+	}
+
+	// An incompatible change keeps the last good value
+	repo.setData("name", []string{"a", "b"})
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if got := ref.Value(); got != "updated" {
+		t.Errorf("Expected last good value 'updated', got '%s'", got)
+	}
+}
+
+// TestConfigRefErrors tests that GetConfigRef reports missing and incompatible configs
+func TestConfigRefErrors(t *testing.T) {
+	client, err := NewClientWithOptions(context.Background(), newMockRepository(), 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := GetConfigRef[string](client, "missing"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+	if _, err := GetConfigRef[[]string](client, "age"); err == nil {
+		t.Error("Expected error for incompatible type")
+	}
+}
+
+// BenchmarkConfigRefValue benchmarks repeated reads of an unchanged config through a ConfigRef.
+func BenchmarkConfigRefValue(b *testing.B) {
+	client, err := NewClientWithOptions(context.Background(), newMockRepository(), 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	ref, err := GetConfigRef[string](client, "name")
+	if err != nil {
+		b.Fatalf("Failed to get ref: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ref.Value()
+	}
+}
+
+// TestClientErr tests that Err reports the outcome of the most recent refresh
+func TestClientErr(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{SetAsDefault: false})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Err(); err != nil {
+		t.Errorf("Expected nil error after successful refresh, got: %v", err)
+	}
+
+	repo.setError(true)
+	_ = client.RefreshNow(context.Background())
+	if err := client.Err(); err == nil {
+		t.Error("Expected error after failed refresh")
+	}
+
+	repo.setError(false)
+	_ = client.RefreshNow(context.Background())
+	if err := client.Err(); err != nil {
+		t.Errorf("Expected nil error after recovery, got: %v", err)
+	}
+}
+
+// TestClientStrict tests that strict clients reject config fields the struct doesn't declare
+func TestClientStrict(t *testing.T) {
+	type Database struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	repo := newMockRepository()
+	repo.setData("database", map[string]interface{}{"host": "db", "port": 5432, "prot": 5433})
+
+	lenient, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer lenient.Close()
+	var db Database
+	if err := lenient.GetConfig("database", &db, Database{}); err != nil {
+		t.Errorf("Expected unknown field to be ignored, got: %v", err)
+	}
+	if db.Host != "db" || db.Port != 5432 {
+		t.Errorf("Expected {db 5432}, got %+v", db)
+	}
+
+	strict, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer strict.Close()
+	err = strict.GetConfig("database", &db, Database{Host: "default"})
+	if err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("Expected error naming the unknown field, got: %v", err)
+	}
+	if db.Host != "default" {
+		t.Errorf("Expected default value on error, got %+v", db)
+	}
+
+	// Scalars and maps are unaffected
+	if name, err := strict.GetConfigString("name", ""); err != nil || name != "test" {
+		t.Errorf("Expected strict client to read scalars, got %q (err: %v)", name, err)
+	}
+	var raw map[string]interface{}
+	if err := strict.GetConfig("database", &raw, nil); err != nil {
+		t.Errorf("Expected strict client to decode into a map, got: %v", err)
+	}
+}
+
+// TestClientWaitForLoad tests that reads wait for the first successful refresh before reporting a missing key
+func TestClientWaitForLoad(t *testing.T) {
+	repo := newMockRepository()
+	repo.replaceData(map[string]interface{}{})
+	repo.setError(true)
+	client, err := NewClientWithOptions(context.Background(), repo, 20*time.Millisecond, ClientOptions{
+		Lazy:        true,
+		WaitForLoad: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// The first refresh fails; a later background refresh succeeds
+	time.AfterFunc(100*time.Millisecond, func() {
+		repo.replaceData(map[string]interface{}{"name": "test"})
+		repo.setError(false)
+	})
+
+	start := time.Now()
+	name, err := client.GetConfigString("name", "default")
+	if err != nil || name != "test" {
+		t.Errorf("Expected read to wait for config, got %q (err: %v)", name, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected read to wait for the first successful refresh, returned after %v", elapsed)
+	}
+
+	// Once loaded, missing keys are reported immediately
+	start = time.Now()
+	if _, err := client.GetConfigString("missing", ""); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected missing key after load not to wait, took %v", elapsed)
+	}
+}
+
+// TestClientWaitForLoadTimeout tests that reads give up after WaitForLoad
+func TestClientWaitForLoadTimeout(t *testing.T) {
+	repo := newMockRepository()
+	repo.replaceData(map[string]interface{}{})
+	repo.setError(true)
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{
+		Lazy:        true,
+		WaitForLoad: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	if _, err := client.GetConfigString("name", ""); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound after timeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected read to wait for WaitForLoad, returned after %v", elapsed)
+	}
+}
+
+// TestClientClone tests that a clone reads the same data without fetching again and has its own lifecycle
+func TestClientClone(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	clone := client.Clone(context.Background(), 2*time.Hour, ClientOptions{})
+	defer clone.Close()
+	if clone.Repository != client.Repository {
+		t.Error("Expected the clone to share the repository")
+	}
+	if clone.RefreshInterval != 2*time.Hour {
+		t.Errorf("Expected clone refresh interval 2h, got %v", clone.RefreshInterval)
+	}
+	if count := repo.getRefreshCount(); count != 1 {
+		t.Errorf("Expected 1 refresh, got %d", count)
+	}
+	if status := clone.GetRefreshStatus(); status.RefreshCount != 1 {
+		t.Errorf("Expected the clone to start with the parent's refresh count, got %d", status.RefreshCount)
+	}
+
+	client.Close()
+	name, err := clone.GetConfigString("name", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if name != "test" {
+		t.Errorf("Expected 'test', got '%s'", name)
+	}
+	if clone.IsClosed() {
+		t.Error("Expected closing the parent to leave the clone open")
+	}
+}
+
+// TestClientCloneSharesRefresh tests that a refresh through a clone invalidates the parent's cache
+func TestClientCloneSharesRefresh(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+	clone := client.Clone(context.Background(), 1*time.Hour, ClientOptions{})
+	defer clone.Close()
+
+	if name, _ := client.GetConfigString("name", ""); name != "test" {
+		t.Fatalf("Expected 'test', got '%s'", name)
+	}
+	repo.setData("name", "updated")
+	if err := clone.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, c := range []*Client{client, clone} {
+		if name, _ := c.GetConfigString("name", ""); name != "updated" {
+			t.Errorf("Expected 'updated', got '%s'", name)
+		}
+		if status := c.GetRefreshStatus(); status.RefreshCount != 2 {
+			t.Errorf("Expected refresh count 2, got %d", status.RefreshCount)
+		}
+	}
+}
+
+// TestClientWaitForConfig tests that WaitForConfig returns once a refresh makes the value satisfy the predicate
+func TestClientWaitForConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("rollout: 10\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	repo := &source.FileRepository{Name: "config", Path: path}
+	client, err := NewClientWithOptions(context.Background(), repo, 20*time.Millisecond, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	fullRollout := func(v interface{}) bool { return v == 100 }
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(path, []byte("rollout: 100\n"), 0o644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.WaitForConfig(ctx, "rollout", fullRollout); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if rollout, _ := client.GetConfigInt("rollout", 0); rollout != 100 {
+		t.Errorf("Expected rollout 100, got %d", rollout)
+	}
+
+	// Already satisfied: returns without waiting for a refresh
+	if err := client.WaitForConfig(context.Background(), "rollout", fullRollout); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+// TestClientWaitForConfigTimeout tests that WaitForConfig gives up when the context expires or the client closes
+func TestClientWaitForConfigTimeout(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 20*time.Millisecond, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+	never := func(interface{}) bool { return false }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.WaitForConfig(ctx, "name", never); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		client.Close()
+	}()
+	if err := client.WaitForConfig(context.Background(), "missing", never); err == nil {
+		t.Error("Expected error after the client was closed")
+	}
+}
+
+// TestClientGetConfigFirst tests that the first present name is decoded and reported
+func TestClientGetConfigFirst(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("limit.default", 10)
+	repo.setData("limit.tenant-a", 50)
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		names     []string
+		wantName  string
+		wantLimit int
+	}{
+		{[]string{"limit.tenant-a", "limit.default"}, "limit.tenant-a", 50},
+		{[]string{"limit.tenant-b", "limit.default"}, "limit.default", 10},
+	}
+	for _, tt := range tests {
+		var limit int
+		name, err := client.GetConfigFirst(&limit, tt.names...)
+		if err != nil {
+			t.Errorf("%v: Expected no error, got: %v", tt.names, err)
+		}
+		if name != tt.wantName || limit != tt.wantLimit {
+			t.Errorf("%v: Expected %s = %d, got %s = %d", tt.names, tt.wantName, tt.wantLimit, name, limit)
+		}
+	}
+
+	limit := 5
+	name, err := client.GetConfigFirst(&limit, "limit.tenant-b", "limit.tenant-c")
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+	if name != "" || limit != 5 {
+		t.Errorf("Expected no match and dest unchanged, got %q and %d", name, limit)
+	}
+}
+
+// TestClientGetConfigBigNumbers tests that big integers and long decimals are read without precision loss
+func TestClientGetConfigBigNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "id: 303984756986439880155862132370440192\nprice: 0.1234567890123456789\ncount: 42\nratio: 0.5\nname: test\n" +
+		"padded: \"0123\"\nhex: \"0x1F\"\nunderscored: \"1_000\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	repo := &source.FileRepository{Name: "config", Path: path, DecodeOptions: source.DecodeOptions{PreserveBigNumbers: true}}
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	id, err := client.GetConfigBigInt("id", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if id.String() != "303984756986439880155862132370440192" {
+		t.Errorf("Expected exact id, got %s", id)
+	}
+	count, err := client.GetConfigBigInt("count", nil)
+	if err != nil || count.Int64() != 42 {
+		t.Errorf("Expected count 42, got %v (%v)", count, err)
+	}
+	if _, err := client.GetConfigBigInt("ratio", nil); err == nil {
+		t.Error("Expected error for a fractional value")
+	}
+	if n, err := client.GetConfigBigInt("padded", nil); err != nil || n.Int64() != 123 {
+		t.Errorf("Expected a leading-zero string read as decimal 123, got %v (%v)", n, err)
+	}
+	for _, key := range []string{"hex", "underscored"} {
+		if _, err := client.GetConfigBigInt(key, nil); err == nil {
+			t.Errorf("%s: Expected error for a non-decimal string", key)
+		}
+	}
+
+	price, err := client.GetConfigBigFloat("price", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := price.Text('f', 19); got != "0.1234567890123456789" {
+		t.Errorf("Expected exact price, got %s", got)
+	}
+	ratio, err := client.GetConfigBigFloat("ratio", nil)
+	if err != nil || ratio.Cmp(big.NewFloat(0.5)) != 0 {
+		t.Errorf("Expected ratio 0.5, got %v (%v)", ratio, err)
+	}
+
+	defaultValue := big.NewFloat(1)
+	if f, err := client.GetConfigBigFloat("name", defaultValue); err == nil || f != defaultValue {
+		t.Errorf("Expected default and error for a non-numeric value, got %v (%v)", f, err)
+	}
+	if _, err := client.GetConfigBigInt("missing", nil); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+}
+
+// TestClientSetRefreshInterval tests that the background refresh follows the new interval immediately
+func TestClientSetRefreshInterval(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := repo.getRefreshCount(); got != 1 {
+		t.Fatalf("Expected only the initial refresh, got %d", got)
+	}
+
+	client.SetRefreshInterval(10 * time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.getRefreshCount() < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := repo.getRefreshCount(); got < 4 {
+		t.Fatalf("Expected refreshes every 10ms after the change, got %d", got)
+	}
+
+	// Backing off stops the fast refreshes and widens the staleness window
+	client.SetRefreshInterval(1 * time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	before := repo.getRefreshCount()
+	time.Sleep(100 * time.Millisecond)
+	if got := repo.getRefreshCount(); got != before {
+		t.Errorf("Expected no refreshes after backing off, got %d more", got-before)
+	}
+	if client.GetRefreshStatus().IsStale {
+		t.Error("Expected data not to be stale under the longer interval")
+	}
+
+	client.SetRefreshInterval(0)
+	if client.RefreshInterval != 1*time.Hour {
+		t.Errorf("Expected a non-positive interval to be ignored, got %v", client.RefreshInterval)
+	}
+}
+
+// TestClientGetConfigURL tests that URL values are parsed and must be absolute
+func TestClientGetConfigURL(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("webhook", "https://hooks.example.com/notify?team=payments")
+	repo.setData("relative", "/notify")
+	repo.setData("no_scheme", "hooks.example.com/notify")
+	repo.setData("garbage", "http://[::1")
+	repo.setData("ftp", "ftp://files.example.com/config")
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	u, err := client.GetConfigURL("webhook", "http", "HTTPS")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if u.Host != "hooks.example.com" || u.Path != "/notify" || u.Query().Get("team") != "payments" {
+		t.Errorf("Expected parsed webhook URL, got %v", u)
+	}
+
+	for _, name := range []string{"relative", "no_scheme", "garbage", "age"} {
+		if u, err := client.GetConfigURL(name); err == nil || u != nil {
+			t.Errorf("%s: Expected an error and nil URL, got %v (%v)", name, u, err)
+		}
+	}
+	if _, err := client.GetConfigURL("ftp", "http", "https"); err == nil || !strings.Contains(err.Error(), "scheme") {
+		t.Errorf("Expected disallowed scheme error, got: %v", err)
+	}
+	if _, err := client.GetConfigURL("ftp"); err != nil {
+		t.Errorf("Expected any scheme without allowedSchemes, got: %v", err)
+	}
+	if _, err := client.GetConfigURL("missing"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+}
+
+// TestClientGetConfigInto tests that a partial config overrides only the keys it sets
+func TestClientGetConfigInto(t *testing.T) {
+	type Backoff struct {
+		Initial int `yaml:"initial"`
+		Max     int `yaml:"max"`
+	}
+	type Settings struct {
+		Timeout int               `yaml:"timeout"`
+		Retries int               `yaml:"retries"`
+		Backoff Backoff           `yaml:"backoff"`
+		Labels  map[string]string `yaml:"labels"`
+	}
+	defaults := func() Settings {
+		return Settings{
+			Timeout: 30,
+			Retries: 3,
+			Backoff: Backoff{Initial: 1, Max: 60},
+			Labels:  map[string]string{"team": "payments"},
+		}
+	}
+
+	repo := newMockRepository()
+	repo.setData("settings", map[string]interface{}{
+		"retries": 5,
+		"backoff": map[string]interface{}{"max": 120},
+		"labels":  map[string]interface{}{"tier": "gold"},
+	})
+	repo.setData("broken", map[string]interface{}{"retries": "many", "timeout": 1})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	settings := defaults()
+	if err := client.GetConfigInto("settings", &settings); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := Settings{
+		Timeout: 30,
+		Retries: 5,
+		Backoff: Backoff{Initial: 1, Max: 120},
+		Labels:  map[string]string{"team": "payments", "tier": "gold"},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Expected %+v, got %+v", want, settings)
+	}
+
+	// Failed decodes and missing keys leave the defaults untouched
+	for _, name := range []string{"broken", "missing"} {
+		settings := defaults()
+		if err := client.GetConfigInto(name, &settings); err == nil {
+			t.Errorf("%s: Expected an error", name)
+		}
+		if !reflect.DeepEqual(settings, defaults()) {
+			t.Errorf("%s: Expected defaults to be kept, got %+v", name, settings)
+		}
+	}
+
+	if err := client.GetConfigInto("settings", Settings{}); err == nil {
+		t.Error("Expected an error for a non-pointer")
+	}
+}
+
+// TestClientEvalBool tests plain, rule-targeted and percentage-rollout flags
+func TestClientEvalBool(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("plain_on", true)
+	repo.setData("killed", map[string]interface{}{
+		"enabled": false,
+		"rules":   []interface{}{map[string]interface{}{"attribute": "country", "values": []interface{}{"CA"}, "enabled": true}},
+	})
+	repo.setData("targeted", map[string]interface{}{
+		"enabled": true,
+		"rules": []interface{}{
+			map[string]interface{}{"attribute": "plan", "values": []interface{}{"free"}, "enabled": false},
+			map[string]interface{}{"attribute": "country", "values": []interface{}{"CA", "US"}, "enabled": true},
+			map[string]interface{}{"attribute": "org_id", "values": []interface{}{42}, "enabled": true},
+		},
+		"rollout_percent": 0,
+	})
+	repo.setData("targeted_implicit", map[string]interface{}{
+		"rules":           []interface{}{map[string]interface{}{"attribute": "country", "values": []interface{}{"CA"}, "enabled": true}},
+		"rollout_percent": 0,
+	})
+	repo.setData("rollout_implicit", map[string]interface{}{"rollout_percent": 100})
+	repo.setData("not_a_flag", "yes")
+	repo.setData("empty_flag", map[string]interface{}{"bucket_by": "user_id"})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		flag       string
+		attributes map[string]interface{}
+		want       bool
+	}{
+		{"plain_on", nil, true},
+		{"killed", map[string]interface{}{"country": "CA"}, false},
+		{"targeted", map[string]interface{}{"country": "CA"}, true},
+		{"targeted", map[string]interface{}{"country": "CA", "plan": "free"}, false},
+		{"targeted", map[string]interface{}{"org_id": "42"}, true},
+		{"targeted", map[string]interface{}{"country": "FR"}, false},
+		{"targeted", nil, false},
+		{"targeted_implicit", map[string]interface{}{"country": "CA"}, true},
+		{"targeted_implicit", map[string]interface{}{"country": "FR"}, false},
+		{"rollout_implicit", map[string]interface{}{"key": "user-1"}, true},
+	}
+	for _, tt := range tests {
+		got, err := client.EvalBool(tt.flag, tt.attributes)
+		if err != nil {
+			t.Errorf("%s %v: Expected no error, got: %v", tt.flag, tt.attributes, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s %v: Expected %v, got %v", tt.flag, tt.attributes, tt.want, got)
+		}
+	}
+
+	if _, err := client.EvalBool("not_a_flag", nil); err == nil {
+		t.Error("Expected an error for a non-flag value")
+	}
+	if _, err := client.EvalBool("empty_flag", nil); err == nil {
+		t.Error("Expected an error for a flag without enabled, rules or rollout_percent")
+	}
+	if _, err := client.EvalBool("missing", nil); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+	}
+}
+
+// TestClientEvalBoolRollout tests that percentage rollouts are deterministic and close to the percentage
+func TestClientEvalBoolRollout(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("rollout", map[string]interface{}{"enabled": true, "rollout_percent": 25, "bucket_by": "user_id"})
+	repo.setData("everyone", map[string]interface{}{"enabled": true, "rollout_percent": 100})
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+
+	on := 0
+	for i := 0; i < 10000; i++ {
+		attributes := map[string]interface{}{"user_id": fmt.Sprintf("user-%d", i)}
+		first, err := client.EvalBool("rollout", attributes)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if second, _ := client.EvalBool("rollout", attributes); second != first {
+			t.Fatalf("Expected the same result for user-%d on every call", i)
+		}
+		if first {
+			on++
+		}
+	}
+	if on < 2200 || on > 2800 {
+		t.Errorf("Expected about 2500 of 10000 users in a 25%% rollout, got %d", on)
+	}
+
+	// Subjects without the bucketing attribute are only in full rollouts
+	if got, _ := client.EvalBool("rollout", nil); got {
+		t.Error("Expected a partial rollout to be off without user_id")
+	}
+	if got, _ := client.EvalBool("everyone", nil); !got {
+		t.Error("Expected a 100% rollout to be on without a key")
+	}
+}
+
+// panickingRepository is a mockRepository whose Refresh panics once panics is set
+type panickingRepository struct {
+	*mockRepository
+	panics atomic.Bool
+}
+
+func (p *panickingRepository) Refresh() error {
+	if p.panics.Load() {
+		panic("decoder bug")
+	}
+	return p.mockRepository.Refresh()
+}
+
+// TestClientRefreshPanic tests that a panicking refresh is recorded as an error instead of crashing
+func TestClientRefreshPanic(t *testing.T) {
+	repo := &panickingRepository{mockRepository: newMockRepository()}
+	client, err := NewClient(context.Background(), repo, 1*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	repo.panics.Store(true)
+	err = client.RefreshNow(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "refresh panicked: decoder bug") {
+		t.Fatalf("Expected the panic as the refresh error, got '%v'", err)
+	}
+	if status := client.GetRefreshStatus(); status.LastRefreshErr == nil {
+		t.Error("Expected the panic to be recorded as the last refresh error")
+	}
+
+	// The previously loaded data is still served
+	var name string
+	if err := client.GetConfig("name", &name, nil); err != nil || name != "test" {
+		t.Errorf("Expected 'test' after the panic, got '%s' (%v)", name, err)
+	}
+
+	repo.panics.Store(false)
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Errorf("Expected the next refresh to succeed, got '%v'", err)
+	}
+}
+
+// TestClientKeyDelimiter tests nested path lookups and their precedence over literal dotted keys
+func TestClientKeyDelimiter(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("feature.v2.enabled", "literal")
+	repo.setData("feature", map[string]interface{}{
+		"v2": map[string]interface{}{"enabled": "nested"},
+	})
+	repo.setData("only.literal", "literal")
+
+	tests := []struct {
+		name string
+		opts ClientOptions
+		key  string
+		want string
+	}{
+		{"no delimiter reads literal keys", ClientOptions{}, "feature.v2.enabled", "literal"},
+		{"nested path wins by default", ClientOptions{KeyDelimiter: "."}, "feature.v2.enabled", "nested"},
+		{"literal key wins when preferred", ClientOptions{KeyDelimiter: ".", PreferLiteralKeys: true}, "feature.v2.enabled", "literal"},
+		{"literal key when no path", ClientOptions{KeyDelimiter: "."}, "only.literal", "literal"},
+		{"custom delimiter", ClientOptions{KeyDelimiter: "/"}, "feature/v2/enabled", "nested"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer client.Close()
+			var got string
+			if err := client.GetConfig(tt.key, &got, nil); err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{KeyDelimiter: "."})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// Snapshots resolve paths like their client
+	snapshot, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	if got, err := snapshot.GetConfigString("feature.v2.enabled", ""); err != nil || got != "nested" {
+		t.Errorf("Expected 'nested' from the snapshot, got '%s' (%v)", got, err)
+	}
+
+	for _, key := range []string{"feature.v3.enabled", "feature.v2.enabled.more", "name.first"} {
+		var got string
+		if err := client.GetConfig(key, &got, nil); !errors.Is(err, ErrConfigNotFound) {
+			t.Errorf("%s: Expected ErrConfigNotFound, got '%v'", key, err)
+		}
+	}
+}
+
+// TestClientGetConfigFormatted tests the GetConfig*f methods that format the key name
+func TestClientGetConfigFormatted(t *testing.T) {
+	repo := newMockRepository()
+	repo.setData("limits.acme.max", 100)
+	repo.setData("plan.acme", "gold")
+	repo.setData("beta.acme", true)
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if got, err := client.GetConfigIntf(10, "limits.%s.max", "acme"); err != nil || got != 100 {
+		t.Errorf("Expected 100, got %d (%v)", got, err)
+	}
+	if got, err := client.GetConfigStringf("free", "plan.%s", "acme"); err != nil || got != "gold" {
+		t.Errorf("Expected 'gold', got '%s' (%v)", got, err)
+	}
+	if got, err := client.GetConfigBoolf(false, "beta.%s", "acme"); err != nil || !got {
+		t.Errorf("Expected true, got %v (%v)", got, err)
+	}
+
+	// Missing formatted keys return the defaults
+	if got, err := client.GetConfigIntf(10, "limits.%s.max", "globex"); !errors.Is(err, ErrConfigNotFound) || got != 10 {
+		t.Errorf("Expected 10 and ErrConfigNotFound, got %d (%v)", got, err)
+	}
+	if got, err := client.GetConfigStringf("free", "plan.%s", "globex"); !errors.Is(err, ErrConfigNotFound) || got != "free" {
+		t.Errorf("Expected 'free' and ErrConfigNotFound, got '%s' (%v)", got, err)
+	}
+	if got, err := client.GetConfigBoolf(true, "beta.%s", "globex"); !errors.Is(err, ErrConfigNotFound) || !got {
+		t.Errorf("Expected true and ErrConfigNotFound, got %v (%v)", got, err)
+	}
+
+	// The key is formatted with the usual verbs
+	if got, err := client.GetConfigBoolf(false, "beta.%v", "acme"); err != nil || !got {
+		t.Errorf("Expected true, got %v (%v)", got, err)
+	}
+	if _, err := client.GetConfigBool("name", false); err == nil {
+		t.Error("Expected an error for a non-bool config")
+	}
+}
+
+// TestClientHealthCheck tests HealthCheck for fresh, failed, stale and closed clients
+func TestClientHealthCheck(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.HealthCheck(); err != nil {
+		t.Errorf("Expected a fresh client to be healthy, got '%v'", err)
+	}
+
+	// A failed refresh is reported even though the data is still fresh
+	repo.setError(true)
+	refreshErr := client.RefreshNow(context.Background())
+	err = client.HealthCheck()
+	if err == nil || !errors.Is(err, refreshErr) {
+		t.Errorf("Expected the refresh error to be wrapped, got '%v'", err)
+	}
+	if !client.IsHealthy() {
+		t.Error("Expected IsHealthy to tolerate the failed refresh")
+	}
+
+	repo.setError(false)
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected the refresh to succeed, got '%v'", err)
+	}
+	if err := client.HealthCheck(); err != nil {
+		t.Errorf("Expected the client to recover, got '%v'", err)
+	}
+
+	// Data older than twice the refresh interval is stale
+	client.mu.Lock()
+	client.lastRefreshTime = time.Now().Add(-3 * time.Hour)
+	client.mu.Unlock()
+	if err := client.HealthCheck(); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Expected a stale error, got '%v'", err)
+	}
+
+	client.Close()
+	if err := client.HealthCheck(); err == nil {
+		t.Error("Expected a closed client to be unhealthy")
+	}
+}
+
+// TestClientHealthCheckNotLoaded tests that HealthCheck fails before the first successful refresh
+func TestClientHealthCheckNotLoaded(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{Lazy: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if err := client.HealthCheck(); err == nil || !strings.Contains(err.Error(), "not been loaded") {
+		t.Errorf("Expected a not loaded error, got '%v'", err)
+	}
+}
+
+// flakyRepository is a mockRepository whose first failures refreshes fail
+type flakyRepository struct {
+	*mockRepository
+	failures atomic.Int32
+	attempts atomic.Int32
+}
+
+func (f *flakyRepository) Refresh() error {
+	f.attempts.Add(1)
+	if f.failures.Add(-1) >= 0 {
+		return errors.New("transient failure")
+	}
+	return f.mockRepository.Refresh()
+}
+
+// TestClientInitialRetries tests that a failed initial refresh is retried within the budget
+func TestClientInitialRetries(t *testing.T) {
+	repo := &flakyRepository{mockRepository: newMockRepository()}
+	repo.failures.Store(2)
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{
+		InitialRetries:      3,
+		InitialRetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected the retries to succeed, got '%v'", err)
+	}
+	defer client.Close()
+	if got := repo.attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+	if name, _ := client.GetConfigString("name", ""); name != "test" {
+		t.Errorf("Expected 'test', got '%s'", name)
+	}
+
+	// The final error is returned once the retries are exhausted
+	repo = &flakyRepository{mockRepository: newMockRepository()}
+	repo.failures.Store(5)
+	_, err = NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{
+		InitialRetries:      2,
+		InitialRetryBackoff: time.Millisecond,
+	})
+	if err == nil || err.Error() != "transient failure" {
+		t.Errorf("Expected the refresh error after the retries, got '%v'", err)
+	}
+	if got := repo.attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+// TestClientInitialRetriesCanceled tests that canceling ctx stops the initial retries
+func TestClientInitialRetriesCanceled(t *testing.T) {
+	repo := &flakyRepository{mockRepository: newMockRepository()}
+	repo.failures.Store(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewClientWithOptions(ctx, repo, 1*time.Hour, ClientOptions{
+		InitialRetries:      10,
+		InitialRetryBackoff: time.Hour,
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected canceling ctx to stop retrying, took %s", elapsed)
+	}
+	if got := repo.attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+// TestClientGetConfigBase64 tests decoding standard, URL-safe and invalid base64 values
+func TestClientGetConfigBase64(t *testing.T) {
+	binary := []byte{0xfb, 0xff, 0xfe, 0x00, 0x01}
+	repo := newMockRepository()
+	repo.setData("std", base64.StdEncoding.EncodeToString(binary))
+	repo.setData("url", base64.URLEncoding.EncodeToString(binary))
+	repo.setData("unpadded", base64.RawURLEncoding.EncodeToString(binary))
+	repo.setData("multiline", "  "+base64.StdEncoding.EncodeToString(binary)+"\n")
+	repo.setData("invalid", "not base64!")
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for _, name := range []string{"std", "url", "unpadded", "multiline"} {
+		got, err := client.GetConfigBase64(name)
+		if err != nil {
+			t.Errorf("%s: Expected no error, got '%v'", name, err)
+		}
+		if !bytes.Equal(got, binary) {
+			t.Errorf("%s: Expected %v, got %v", name, binary, got)
+		}
+	}
+
+	if _, err := client.GetConfigBase64("invalid"); err == nil || !strings.Contains(err.Error(), "not valid base64") {
+		t.Errorf("Expected an invalid base64 error, got '%v'", err)
+	}
+	if _, err := client.GetConfigBase64("age"); err == nil {
+		t.Error("Expected an error for a non-string config")
+	}
+	if _, err := client.GetConfigBase64("missing"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got '%v'", err)
+	}
+}
+
+// refreshObservation is one call of a RefreshObserver
+type refreshObservation struct {
+	repoName string
+	d        time.Duration
+	err      error
+}
+
+// TestClientRefreshObserver tests that the observer receives the duration and outcome of each refresh
+func TestClientRefreshObserver(t *testing.T) {
+	var mu sync.Mutex
+	var observations []refreshObservation
+	repo := newMockRepository()
+	repo.refreshDelay = 20 * time.Millisecond
+	client, err := NewClientWithOptions(context.Background(), repo, 1*time.Hour, ClientOptions{
+		RefreshObserver: func(repoName string, d time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			observations = append(observations, refreshObservation{repoName, d, err})
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	repo.setError(true)
+	refreshErr := client.RefreshNow(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observations) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(observations))
+	}
+	for i, o := range observations {
+		if o.repoName != "mock" {
+			t.Errorf("%d: Expected repository 'mock', got '%s'", i, o.repoName)
+		}
+		if o.d < repo.refreshDelay || o.d > 5*time.Second {
+			t.Errorf("%d: Expected a duration of at least %s, got %s", i, repo.refreshDelay, o.d)
+		}
+	}
+	if observations[0].err != nil {
+		t.Errorf("Expected the initial refresh to succeed, got '%v'", observations[0].err)
+	}
+	if observations[1].err == nil || observations[1].err != refreshErr {
+		t.Errorf("Expected the refresh error '%v', got '%v'", refreshErr, observations[1].err)
+	}
+}
+
+// TestDecodeProtobuf tests that a configuration map round-trips through a protobuf Struct
+func TestDecodeProtobuf(t *testing.T) {
+	config := map[string]interface{}{
+		"name":    "app",
+		"timeout": 30.0,
+		"enabled": true,
+		"hosts":   []interface{}{"a", "b"},
+		"limits":  map[string]interface{}{"rps": 2.5},
+		"unset":   nil,
+	}
+	message, err := structpb.NewStruct(config)
+	if err != nil {
+		t.Fatalf("Failed to build Struct: %v", err)
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to encode Struct: %v", err)
+	}
+
+	decoded, err := DecodeProtobuf(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected %v, got %v", config, decoded)
+	}
+
+	if _, err := DecodeProtobuf([]byte("key: value\n")); err == nil {
+		t.Error("Expected error for data that is not a protobuf Struct")
+	}
+}
+
+// countingConfig counts how often it is decoded
+type countingConfig struct {
+	Limit   int
+	decodes *int
+}
+
+func (c *countingConfig) UnmarshalYAML(value *yaml.Node) error {
+	*c.decodes++
+	var raw struct {
+		Limit int `yaml:"limit"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	c.Limit = raw.Limit
+	return nil
+}
+
+// TestGetConfigWithETag tests that config is only decoded again when the server's ETag changes
+func TestGetConfigWithETag(t *testing.T) {
+	var mu sync.Mutex
+	body, etag := "limits:\n  limit: 10\n", `"v1"`
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	client, err := NewClient(context.Background(), &source.WebRepository{Name: "web", URL: serverURL}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	limits := client.GetConfigWithETag("limits")
+	if !limits.Changed() {
+		t.Error("Expected Changed before the first Get")
+	}
+	decodes := 0
+	config := countingConfig{decodes: &decodes}
+	if err := limits.Get(&config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if config.Limit != 10 || decodes != 1 {
+		t.Errorf("Expected limit 10 decoded once, got %d decoded %d times", config.Limit, decodes)
+	}
+	if limits.ETag() != `"v1"` {
+		t.Errorf("Expected the server's ETag, got %s", limits.ETag())
+	}
+
+	// A 304 leaves the ETag unchanged, so nothing is decoded again
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mu.Lock()
+	if notModified != 1 {
+		t.Errorf("Expected a 304 response, got %d", notModified)
+	}
+	mu.Unlock()
+	if limits.Changed() {
+		t.Error("Expected no change after a 304")
+	}
+	if err := limits.Get(&config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if decodes != 1 {
+		t.Errorf("Expected no decode after a 304, got %d decodes", decodes)
+	}
+
+	// New content with a new ETag is decoded once
+	mu.Lock()
+	body, etag = "limits:\n  limit: 20\n", `"v2"`
+	mu.Unlock()
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !limits.Changed() {
+		t.Error("Expected a change after the ETag changed")
+	}
+	for i := 0; i < 2; i++ {
+		if err := limits.Get(&config); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if config.Limit != 20 || decodes != 2 {
+		t.Errorf("Expected limit 20 decoded once more, got %d decoded %d times", config.Limit, decodes)
+	}
+	if limits.Changed() {
+		t.Error("Expected no change after reading the new content")
+	}
+}
+
+// TestGetConfigWithETagContentHash tests that repositories without ETags are tracked by content hash
+func TestGetConfigWithETagContentHash(t *testing.T) {
+	repo := newMockRepository()
+	client, err := NewClient(context.Background(), repo, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	name := client.GetConfigWithETag("name")
+	var value string
+	if err := name.Get(&value); err != nil || value != "test" {
+		t.Fatalf("Expected 'test', got %q, %v", value, err)
+	}
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if name.Changed() {
+		t.Error("Expected no change after refreshing identical content")
+	}
+
+	repo.setData("name", "updated")
+	if err := client.RefreshNow(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !name.Changed() {
+		t.Fatal("Expected a change after the content changed")
+	}
+	if err := name.Get(&value); err != nil || value != "updated" {
+		t.Errorf("Expected 'updated', got %q, %v", value, err)
+	}
+}
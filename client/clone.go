@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cloneGroup is the state shared by a client and its clones.
+type cloneGroup struct {
+	flight  *singleflight.Group // The original client's refresh group
+	mu      sync.Mutex
+	members []*Client // Open clients sharing the repository
+}
+
+// Clone returns a new client reading the same repository as c, with its own
+// refresh interval, options and lifecycle: closing either client (or
+// canceling ctx) does not stop the other. The clone starts with the data c
+// has already loaded, so no extra fetch is made.
+//
+// Every open client sharing the repository runs its own refresh loop, but
+// concurrent refreshes are coalesced into one backend call and the outcome
+// of any refresh is recorded by all of them, so a refresh made through one
+// client is seen by the others' caches and refresh status. To avoid
+// refreshing the source more often than needed, give clones that only read
+// a long refresh interval. BootstrapPath and Lazy in opts are ignored; the
+// clone serves whatever c currently serves.
+func (c *Client) Clone(ctx context.Context, refreshInterval time.Duration, opts ClientOptions) *Client {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mu.RLock()
+	clone := &Client{
+		Repository:        c.Repository,
+		RefreshInterval:   refreshInterval,
+		cancel:            cancel,
+		done:              ctx.Done(),
+		allowMissingKeys:  opts.AllowMissingKeys,
+		strict:            opts.Strict,
+		keyDelimiter:      opts.KeyDelimiter,
+		preferLiteralKeys: opts.PreferLiteralKeys,
+		lazy:              c.lazy,
+		loaded:            make(chan struct{}),
+		waitForLoad:       opts.WaitForLoad,
+		scheduler:         opts.Scheduler,
+		reschedule:        make(chan struct{}, 1),
+		decryptor:         opts.Decryptor,
+		refreshObserver:   opts.RefreshObserver,
+		bootstrap:         c.bootstrap,
+		lastRefreshTime:   c.lastRefreshTime,
+		lastRefreshErr:    c.lastRefreshErr,
+		refreshCount:      c.refreshCount,
+	}
+	c.mu.RUnlock()
+	if clone.refreshCount > 0 {
+		close(clone.loaded)
+		clone.loadedOnce.Do(func() {})
+	}
+	clone.cache.invalidate(c.cache.version())
+
+	group := c.group.Load()
+	if group == nil {
+		group = &cloneGroup{flight: &c.refreshGroup, members: []*Client{c}}
+		if !c.group.CompareAndSwap(nil, group) {
+			group = c.group.Load()
+		}
+	}
+	group.mu.Lock()
+	group.members = append(group.members, clone)
+	group.mu.Unlock()
+	clone.group.Store(group)
+
+	clone.start(ctx, opts)
+	return clone
+}
+
+// flight returns the single-flight group refreshes of the repository are
+// coalesced in, shared between a client and its clones.
+func (c *Client) flight() *singleflight.Group {
+	if group := c.group.Load(); group != nil {
+		return group.flight
+	}
+	return &c.refreshGroup
+}
+
+// members returns the open clients sharing the repository with c, including
+// c itself.
+func (c *Client) members() []*Client {
+	group := c.group.Load()
+	if group == nil {
+		return []*Client{c}
+	}
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	members := slices.Clone(group.members)
+	if !slices.Contains(members, c) {
+		// Closed, but finishing a refresh started while open
+		members = append(members, c)
+	}
+	return members
+}
+
+// leaveGroup stops c from receiving the refresh results of its clones.
+func (c *Client) leaveGroup() {
+	group := c.group.Load()
+	if group == nil {
+		return
+	}
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	group.members = slices.DeleteFunc(group.members, func(member *Client) bool {
+		return member == c
+	})
+}
@@ -0,0 +1,79 @@
+package client
+
+import "sync"
+
+// ETagConfig tracks a configuration value by the version of the content it
+// was last read from, so embedders can skip expensive re-binding (rebuilding
+// connection pools, recompiling rules, ...) while the config is unchanged.
+// The version is the ETag the config server sent for repositories that
+// expose one, such as a source.WebRepository answered with 304 Not Modified,
+// or else the content hash of the repository data.
+type ETagConfig struct {
+	client *Client
+	name   string
+	mu     sync.Mutex // Serializes reads
+	etag   string     // Version of the content the last successful Get read
+	read   bool       // Whether Get has succeeded yet
+}
+
+// GetConfigWithETag returns an ETagConfig for the configuration with the
+// given name:
+//
+//	limits := client.GetConfigWithETag("limits")
+//	...
+//	if limits.Changed() {
+//		if err := limits.Get(&cfg); err == nil {
+//			rebind(cfg)
+//		}
+//	}
+func (c *Client) GetConfigWithETag(name string) *ETagConfig {
+	return &ETagConfig{client: c, name: name}
+}
+
+// etag returns the version of the content the client currently serves: the
+// active repository's ETag if it has one, or else the content hash.
+func (c *Client) etag() string {
+	c.ensureLoaded()
+	if tagged, ok := c.activeRepository().(interface{ ETag() string }); ok {
+		if etag := tagged.ETag(); etag != "" {
+			return etag
+		}
+	}
+	return c.cache.version()
+}
+
+// Changed reports whether the content changed since the last successful
+// Get, or Get has not succeeded yet. It does not decode anything.
+func (e *ETagConfig) Changed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.read || e.etag != e.client.etag()
+}
+
+// Get decodes the configuration into data, like GetConfig, if the content
+// changed since the last successful Get. Otherwise data is left untouched,
+// so pass the destination the previous Get filled.
+func (e *ETagConfig) Get(data interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// The version is read before decoding, so a refresh in between at worst
+	// tags newer data with an older version and triggers another decode.
+	etag := e.client.etag()
+	if e.read && etag == e.etag {
+		return nil
+	}
+	if err := e.client.GetConfig(e.name, data, nil); err != nil {
+		return err
+	}
+	e.etag = etag
+	e.read = true
+	return nil
+}
+
+// ETag returns the version of the content the last successful Get read, or
+// "" before the first.
+func (e *ETagConfig) ETag() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.etag
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// defaultBucketBy is the attribute percentage rollouts are bucketed on when
+// a flag does not set bucket_by.
+const defaultBucketBy = "key"
+
+// rolloutBuckets is the number of buckets percentage rollouts hash into, so
+// rollout_percent has a resolution of 0.01%.
+const rolloutBuckets = 10000
+
+// flagConfig is the structured form of a feature flag, see EvalBool.
+type flagConfig struct {
+	Enabled        *bool      `yaml:"enabled"`
+	RolloutPercent *float64   `yaml:"rollout_percent"`
+	BucketBy       string     `yaml:"bucket_by"`
+	Rules          []flagRule `yaml:"rules"`
+}
+
+// flagRule targets the attributes whose value is one of Values.
+type flagRule struct {
+	Attribute string        `yaml:"attribute"`
+	Values    []interface{} `yaml:"values"`
+	Enabled   bool          `yaml:"enabled"`
+}
+
+// EvalBool evaluates the feature flag with the given name for a subject
+// described by attributes (e.g. {"key": userID, "country": "CA"}). A flag is
+// either a plain boolean or a map:
+//
+//	new_checkout:
+//	  enabled: true          # kill switch; false turns the flag off for everyone, omit for true
+//	  rules:                 # checked in order, the first match decides
+//	    - attribute: country
+//	      values: [CA, US]
+//	      enabled: true
+//	  rollout_percent: 25    # subjects no rule matched; omit for 100
+//	  bucket_by: key         # attribute hashed for the rollout, defaults to "key"
+//
+// Percentage rollouts hash the flag name with the bucket_by attribute, so a
+// subject gets the same answer on every call and every instance, and raising
+// the percentage only turns the flag on for more subjects. Subjects without
+// the bucket_by attribute are outside any partial rollout. Attribute values
+// are compared to rule values by their string form, so 42 matches "42".
+//
+// A flag without enabled is on wherever its rules and rollout say so: the
+// targeting decides, and enabled only acts as a kill switch. A map with none
+// of enabled, rules and rollout_percent is an error rather than silently
+// off.
+func (c *Client) EvalBool(name string, attributes map[string]interface{}) (bool, error) {
+	if c.closed.Load() {
+		return false, errors.New("client is closed")
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return false, c.missingKeyErr()
+	}
+	if enabled, ok := config.(bool); ok {
+		return enabled, nil
+	}
+	if _, ok := config.(map[string]interface{}); !ok {
+		return false, errors.New("config is not a boolean or a flag")
+	}
+
+	var flag flagConfig
+	if err := c.GetConfig(name, &flag, nil); err != nil {
+		return false, fmt.Errorf("invalid flag %q: %w", name, err)
+	}
+	return flag.eval(name, attributes)
+}
+
+// eval evaluates f for attributes. name seeds the rollout hash.
+func (f flagConfig) eval(name string, attributes map[string]interface{}) (bool, error) {
+	if f.Enabled == nil && f.RolloutPercent == nil && len(f.Rules) == 0 {
+		return false, fmt.Errorf("invalid flag %q: no enabled, rollout_percent or rules", name)
+	}
+	if f.Enabled != nil && !*f.Enabled {
+		return false, nil
+	}
+	for i, rule := range f.Rules {
+		if rule.Attribute == "" {
+			return false, fmt.Errorf("invalid flag %q: rule %d has no attribute", name, i)
+		}
+		if rule.matches(attributes) {
+			return rule.Enabled, nil
+		}
+	}
+	if f.RolloutPercent == nil {
+		return true, nil
+	}
+
+	bucketBy := f.BucketBy
+	if bucketBy == "" {
+		bucketBy = defaultBucketBy
+	}
+	subject, ok := attributes[bucketBy]
+	if !ok {
+		return *f.RolloutPercent >= 100, nil
+	}
+	return float64(rolloutBucket(name, subject)) < *f.RolloutPercent*rolloutBuckets/100, nil
+}
+
+// matches reports whether the rule's attribute is one of its values.
+func (r flagRule) matches(attributes map[string]interface{}) bool {
+	value, ok := attributes[r.Attribute]
+	if !ok {
+		return false
+	}
+	for _, v := range r.Values {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutBucket deterministically maps a subject of a flag to a bucket in
+// [0, rolloutBuckets). Including the flag name keeps the rollouts of
+// different flags independent.
+func rolloutBucket(name string, subject interface{}) uint32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%v", name, subject)
+	return h.Sum32() % rolloutBuckets
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/sardine-ai/go-remote-config/source"
+)
+
+// lookup finds the value of name in repository. Without a KeyDelimiter,
+// name is a literal top-level key. With one, name is also a path through
+// nested maps, and the two are tried in the order set by PreferLiteralKeys.
+func (c *Client) lookup(repository source.Repository, name string) (interface{}, bool) {
+	if c.keyDelimiter == "" || !strings.Contains(name, c.keyDelimiter) {
+		return repository.GetData(name)
+	}
+	if c.preferLiteralKeys {
+		if value, ok := repository.GetData(name); ok {
+			return value, true
+		}
+		return lookupPath(repository, strings.Split(name, c.keyDelimiter))
+	}
+	if value, ok := lookupPath(repository, strings.Split(name, c.keyDelimiter)); ok {
+		return value, true
+	}
+	return repository.GetData(name)
+}
+
+// lookupPath walks path through nested maps, starting at the top-level key
+// path[0].
+func lookupPath(repository source.Repository, path []string) (interface{}, bool) {
+	value, ok := repository.GetData(path[0])
+	for _, key := range path[1:] {
+		if !ok {
+			return nil, false
+		}
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		value, ok = m[key]
+	}
+	return value, ok
+}
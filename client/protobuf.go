@@ -0,0 +1,20 @@
+package client
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DecodeProtobuf decodes a configuration map encoded as a
+// google.protobuf.Struct, as served by a config server with ServeProtobuf
+// set to requests sent with "Accept: application/x-protobuf". As with JSON,
+// numbers decode to float64; integers beyond 2^53 arrive as decimal strings.
+func DecodeProtobuf(data []byte) (map[string]interface{}, error) {
+	var message structpb.Struct
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("error decoding protobuf config: %w", err)
+	}
+	return message.AsMap(), nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigRef is a typed, live view of a single configuration value. Value
+// returns the cached decoded value and only decodes again after a refresh
+// changes the repository content, so repeated reads on hot paths cost an
+// atomic load and a hash comparison.
+type ConfigRef[T any] struct {
+	client  *Client
+	name    string
+	mu      sync.Mutex // Serializes reloads
+	current atomic.Pointer[refValue[T]]
+}
+
+// refValue is a decoded value tagged with the content hash it was decoded from.
+type refValue[T any] struct {
+	hash  string
+	value T
+}
+
+// GetConfigRef returns a ConfigRef for the configuration with the given name.
+// It returns an error if the configuration cannot be read into a T now, e.g.
+// because it is missing or has an incompatible type.
+func GetConfigRef[T any](c *Client, name string) (*ConfigRef[T], error) {
+	ref := &ConfigRef[T]{client: c, name: name}
+	c.ensureLoaded()
+	hash := c.cache.version()
+	var value T
+	if err := c.GetConfig(name, &value, nil); err != nil {
+		return nil, err
+	}
+	ref.current.Store(&refValue[T]{hash: hash, value: value})
+	return ref, nil
+}
+
+// Value returns the current value of the configuration. If the configuration
+// changed but can no longer be read into a T (e.g. it was removed or its type
+// changed), the error is logged and the last good value is kept.
+func (r *ConfigRef[T]) Value() T {
+	current := r.current.Load()
+	hash := r.client.cache.version()
+	if current.hash == hash {
+		return current.value
+	}
+	return r.reload(hash)
+}
+
+// reload decodes the configuration for content hash, unless a concurrent
+// caller already did.
+func (r *ConfigRef[T]) reload(hash string) T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.current.Load()
+	if current.hash == hash {
+		return current.value
+	}
+
+	// The hash is read before decoding, so a refresh in between at worst
+	// tags a newer value with an older hash and triggers another reload.
+	next := &refValue[T]{hash: hash, value: current.value}
+	var value T
+	if err := r.client.GetConfig(r.name, &value, nil); err != nil {
+		logrus.WithError(err).WithField("config", r.name).Warn("error reloading config, keeping last value")
+	} else {
+		next.value = value
+	}
+	r.current.Store(next)
+	return next.value
+}
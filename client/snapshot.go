@@ -0,0 +1,144 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"github.com/sardine-ai/go-remote-config/source"
+)
+
+// Snapshot is an immutable, point-in-time view of a client's configuration.
+// Reads from a snapshot are consistent with each other even if the client
+// refreshes in the meantime, so a request handler can take one snapshot and
+// read many keys without observing a mix of old and new config.
+type Snapshot struct {
+	client *Client
+}
+
+// Snapshot returns a frozen copy of the configuration currently loaded by the
+// client. The snapshot follows the client's AllowMissingKeys, Strict,
+// KeyDelimiter and PreferLiteralKeys options and uses its Decryptor.
+func (c *Client) Snapshot() (*Snapshot, error) {
+	if c.closed.Load() {
+		return nil, errors.New("client is closed")
+	}
+	c.ensureLoaded()
+
+	repository := c.activeRepository()
+	c.mu.RLock()
+	refreshInterval := c.RefreshInterval
+	c.mu.RUnlock()
+	frozen := &frozenRepository{
+		name: repository.GetName(),
+		typ:  repository.Type(),
+		data: source.AllData(repository),
+	}
+	return &Snapshot{
+		client: &Client{
+			Repository:        frozen,
+			RefreshInterval:   refreshInterval,
+			cancel:            func() {},
+			allowMissingKeys:  c.allowMissingKeys,
+			strict:            c.strict,
+			keyDelimiter:      c.keyDelimiter,
+			preferLiteralKeys: c.preferLiteralKeys,
+			decryptor:         c.decryptor,
+		},
+	}, nil
+}
+
+// GetConfig retrieves the configuration with the given name from the snapshot.
+// See Client.GetConfig.
+func (s *Snapshot) GetConfig(name string, data interface{}, defaultValue interface{}) error {
+	return s.client.GetConfig(name, data, defaultValue)
+}
+
+// GetConfigValidated retrieves and validates a configuration from the snapshot.
+// See Client.GetConfigValidated.
+func (s *Snapshot) GetConfigValidated(name string, data interface{}, validate func(interface{}) error) error {
+	return s.client.GetConfigValidated(name, data, validate)
+}
+
+// GetConfigDecrypt retrieves and decrypts a configuration from the snapshot.
+// See Client.GetConfigDecrypt.
+func (s *Snapshot) GetConfigDecrypt(name string, data interface{}) error {
+	return s.client.GetConfigDecrypt(name, data)
+}
+
+// GetConfigString retrieves a string from the snapshot. See Client.GetConfigString.
+func (s *Snapshot) GetConfigString(name string, defaultValue string) (string, error) {
+	return s.client.GetConfigString(name, defaultValue)
+}
+
+// GetConfigInt retrieves an int from the snapshot. See Client.GetConfigInt.
+func (s *Snapshot) GetConfigInt(name string, defaultValue int) (int, error) {
+	return s.client.GetConfigInt(name, defaultValue)
+}
+
+// GetConfigFloat retrieves a float64 from the snapshot. See Client.GetConfigFloat.
+func (s *Snapshot) GetConfigFloat(name string, defaultValue float64) (float64, error) {
+	return s.client.GetConfigFloat(name, defaultValue)
+}
+
+// GetConfigArrayOfStrings retrieves a string array from the snapshot.
+// See Client.GetConfigArrayOfStrings.
+func (s *Snapshot) GetConfigArrayOfStrings(name string, defaultValue []string) ([]string, error) {
+	return s.client.GetConfigArrayOfStrings(name, defaultValue)
+}
+
+// GetConfigEnum retrieves an enum value from the snapshot. See Client.GetConfigEnum.
+func (s *Snapshot) GetConfigEnum(name string, allowed []string, defaultValue string) (string, error) {
+	return s.client.GetConfigEnum(name, allowed, defaultValue)
+}
+
+// GetConfigSlice retrieves a sequence from the snapshot. See Client.GetConfigSlice.
+func (s *Snapshot) GetConfigSlice(name string, dest interface{}) error {
+	return s.client.GetConfigSlice(name, dest)
+}
+
+// Keys returns the sorted top-level configuration names in the snapshot.
+// See Client.Keys.
+func (s *Snapshot) Keys() []string {
+	return s.client.Keys()
+}
+
+// frozenRepository is a read-only Repository over a fixed data map. Refresh
+// is a no-op, so the data never changes.
+type frozenRepository struct {
+	name string
+	typ  string
+	data map[string]interface{}
+}
+
+func (f *frozenRepository) GetName() string {
+	return f.name
+}
+
+func (f *frozenRepository) Type() string {
+	return f.typ
+}
+
+func (f *frozenRepository) GetData(configName string) (interface{}, bool) {
+	config, ok := f.data[configName]
+	return config, ok
+}
+
+func (f *frozenRepository) GetAllData() map[string]interface{} {
+	return deepcopy.Map(f.data)
+}
+
+func (f *frozenRepository) Keys() []string {
+	keys := make([]string, 0, len(f.data))
+	for key := range f.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (f *frozenRepository) GetRawData() []byte {
+	return nil
+}
+
+func (f *frozenRepository) Refresh() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// WaitForConfig blocks until the configuration with the given name is
+// present and satisfies predicate, checking the current value first and
+// again after every successful refresh. It returns ctx.Err() if ctx is done
+// first, or an error if the client is closed while waiting. Use it in
+// integration tests or rollouts that must not proceed before config reaches
+// a desired state:
+//
+//	err := configClient.WaitForConfig(ctx, "feature_x", func(v interface{}) bool {
+//		return v == true
+//	})
+//
+// predicate receives the decoded value (see Repository.GetData) and must not
+// modify it.
+func (c *Client) WaitForConfig(ctx context.Context, name string, predicate func(interface{}) bool) error {
+	c.ensureLoaded()
+	for {
+		if c.closed.Load() {
+			return errors.New("client is closed")
+		}
+		// Take the signal before reading so a refresh in between is not missed
+		refreshed := c.refreshSignal()
+		if value, ok := c.lookup(c.activeRepository(), name); ok && predicate(value) {
+			return nil
+		}
+		select {
+		case <-refreshed:
+		case <-c.done:
+			return errors.New("client is closed")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// refreshSignal returns a channel closed by the next successful refresh.
+func (c *Client) refreshSignal() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshed == nil {
+		c.refreshed = make(chan struct{})
+	}
+	return c.refreshed
+}
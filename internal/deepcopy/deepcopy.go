@@ -0,0 +1,45 @@
+// Package deepcopy copies decoded configuration trees so that values handed
+// to callers never share maps or slices with a repository's internal state.
+package deepcopy
+
+// Map returns a deep copy of m. A nil map is returned as nil.
+func Map(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = Value(v)
+	}
+	return out
+}
+
+// Value returns a deep copy of v. Maps and slices produced by YAML decoding
+// are copied recursively; any other value is returned as is, since decoded
+// scalars are immutable.
+func Value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return Map(v)
+	case map[interface{}]interface{}:
+		if v == nil {
+			return v
+		}
+		out := make(map[interface{}]interface{}, len(v))
+		for k, val := range v {
+			out[k] = Value(val)
+		}
+		return out
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = Value(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
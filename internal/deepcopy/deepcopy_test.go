@@ -0,0 +1,46 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMapIsIndependent tests that mutating a copy leaves the original untouched at every level
+func TestMapIsIndependent(t *testing.T) {
+	original := map[string]interface{}{
+		"name": "app",
+		"database": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+		},
+		"ports": map[interface{}]interface{}{80: "http"},
+	}
+
+	copied := Map(original)
+	if !reflect.DeepEqual(copied, original) {
+		t.Fatalf("Expected copy to equal original, got %v", copied)
+	}
+
+	copied["name"] = "changed"
+	db := copied["database"].(map[string]interface{})
+	db["user"] = "root"
+	db["hosts"].([]interface{})[0] = "z"
+	copied["ports"].(map[interface{}]interface{})[443] = "https"
+
+	want := map[string]interface{}{
+		"name": "app",
+		"database": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+		},
+		"ports": map[interface{}]interface{}{80: "http"},
+	}
+	if !reflect.DeepEqual(original, want) {
+		t.Errorf("Expected original to be unaffected, got %v", original)
+	}
+}
+
+// TestMapNil tests that a nil map is copied as nil
+func TestMapNil(t *testing.T) {
+	if Map(nil) != nil {
+		t.Error("Expected nil copy of nil map")
+	}
+}
@@ -0,0 +1,44 @@
+package schedule
+
+import "time"
+
+// Clock is the time source used by RunWithClock. Waits are measured with
+// durations between readings of the same clock, so with SystemClock they use
+// Go's monotonic clock and are not skewed by wall-clock jumps (NTP
+// corrections, VM suspend/resume). Tests can supply a fake to simulate such
+// jumps.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a stoppable timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
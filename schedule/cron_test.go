@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCronNext tests next-fire computation for a range of expressions
+func TestCronNext(t *testing.T) {
+	base := time.Date(2024, time.January, 15, 10, 7, 30, 0, time.UTC) // Monday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2024, 1, 15, 10, 10, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 1, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 0", time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"15,45 * * * *", time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * 3", time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Cron(tt.expr)
+		if err != nil {
+			t.Fatalf("Cron(%q) returned error: %v", tt.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Cron(%q).Next = %v, expected %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+// TestCronNextNeverMatches tests that impossible expressions return the zero time
+func TestCronNextNeverMatches(t *testing.T) {
+	s := MustCron("0 0 30 2 *")
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Expected zero time, got %v", got)
+	}
+}
+
+// TestCronInvalid tests that malformed expressions are rejected
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package schedule decides when clients and servers refresh their
+// repositories. The default policy is a fixed interval; Cron provides
+// wall-clock schedules, and callers can supply their own Scheduler for
+// anything else (backoff, jitter, business hours, ...).
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// Scheduler returns the time of the next refresh after now. Returning the
+// zero time means no further refreshes are scheduled. A server shares one
+// Scheduler across all its repositories, so Next must be safe for
+// concurrent use.
+type Scheduler interface {
+	Next(now time.Time) time.Time
+}
+
+// SchedulerFunc adapts an ordinary function to the Scheduler interface.
+type SchedulerFunc func(now time.Time) time.Time
+
+// Next calls f(now).
+func (f SchedulerFunc) Next(now time.Time) time.Time {
+	return f(now)
+}
+
+// Every returns a Scheduler that refreshes at a fixed interval, measured from
+// the end of the previous refresh.
+func Every(interval time.Duration) Scheduler {
+	return SchedulerFunc(func(now time.Time) time.Time {
+		return now.Add(interval)
+	})
+}
+
+// Run calls fn each time the scheduler fires until ctx is canceled. fn runs
+// on the calling goroutine, so a slow refresh delays the next Next call
+// rather than overlapping with it.
+func Run(ctx context.Context, scheduler Scheduler, fn func()) {
+	RunWithClock(ctx, SystemClock, scheduler, fn)
+}
+
+// RunWithClock is like Run but reads the time from clock. The wait before
+// each call is next.Sub(now) for a single reading of now, so interval
+// schedules keep firing on time across wall-clock jumps.
+func RunWithClock(ctx context.Context, clock Clock, scheduler Scheduler, fn func()) {
+	run(ctx, clock, scheduler, nil, fn)
+}
+
+// RunWithReset is like Run, but a receive from reset abandons the pending
+// wait and asks the scheduler for a new next time, so a scheduler whose policy
+// changed (e.g. a new interval) takes effect immediately instead of after the
+// wait that was already scheduled.
+func RunWithReset(ctx context.Context, scheduler Scheduler, reset <-chan struct{}, fn func()) {
+	run(ctx, SystemClock, scheduler, reset, fn)
+}
+
+// run implements RunWithClock and RunWithReset. A nil reset never fires.
+func run(ctx context.Context, clock Clock, scheduler Scheduler, reset <-chan struct{}, fn func()) {
+	for {
+		now := clock.Now()
+		next := scheduler.Next(now)
+		if next.IsZero() {
+			select {
+			case <-reset:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		timer := clock.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C():
+			fn()
+		case <-reset:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
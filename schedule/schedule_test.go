@@ -0,0 +1,137 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEvery tests the fixed-interval scheduler
+func TestEvery(t *testing.T) {
+	now := time.Now()
+	if got := Every(time.Minute).Next(now); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected %v, got %v", now.Add(time.Minute), got)
+	}
+}
+
+// TestRunFollowsScheduler tests that Run fires exactly when the scheduler says so
+func TestRunFollowsScheduler(t *testing.T) {
+	var calls atomic.Int32
+	scheduler := SchedulerFunc(func(now time.Time) time.Time {
+		if calls.Load() >= 3 {
+			return time.Time{}
+		}
+		return now.Add(10 * time.Millisecond)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, scheduler, func() { calls.Add(1) })
+		close(done)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected 3 calls, got %d", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+// fakeClock is a Clock whose wall time jumps by jump every time a timer is
+// created, simulating NTP corrections between refreshes. The first `fires`
+// timers fire immediately, later ones never; all record the requested duration.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	jump  time.Duration
+	fires int
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if len(c.waits) < c.fires {
+		c.now = c.now.Add(d + c.jump)
+		ch <- c.now
+	}
+	c.waits = append(c.waits, d)
+	return fakeTimer{ch}
+}
+
+type fakeTimer struct {
+	ch chan time.Time
+}
+
+func (t fakeTimer) C() <-chan time.Time { return t.ch }
+func (t fakeTimer) Stop() bool          { return false }
+
+// TestRunWithClockJumps tests that interval refreshes keep their spacing when the wall clock jumps
+func TestRunWithClockJumps(t *testing.T) {
+	for _, jump := range []time.Duration{-time.Hour, time.Hour} {
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), jump: jump, fires: 3}
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		RunWithClock(ctx, clock, Every(time.Minute), func() {
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+		})
+
+		if calls != 3 {
+			t.Errorf("jump %v: expected 3 calls, got %d", jump, calls)
+		}
+		for i, wait := range clock.waits {
+			if wait != time.Minute {
+				t.Errorf("jump %v: expected wait %d to be 1m, got %v", jump, i, wait)
+			}
+		}
+	}
+}
+
+// TestRunWithReset tests that a reset abandons the pending wait and picks up the scheduler's new policy
+func TestRunWithReset(t *testing.T) {
+	var interval atomic.Int64
+	interval.Store(int64(time.Hour))
+	scheduler := SchedulerFunc(func(now time.Time) time.Time {
+		return now.Add(time.Duration(interval.Load()))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reset := make(chan struct{}, 1)
+	var calls atomic.Int32
+	go RunWithReset(ctx, scheduler, reset, func() { calls.Add(1) })
+
+	time.Sleep(50 * time.Millisecond)
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("Expected no calls before the reset, got %d", got)
+	}
+
+	interval.Store(int64(10 * time.Millisecond))
+	reset <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := calls.Load(); got < 3 {
+		t.Errorf("Expected the new interval to apply after the reset, got %d calls", got)
+	}
+}
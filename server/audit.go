@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sardine-ai/go-remote-config/source"
+)
+
+// AuditEvent records a change to the content a repository serves, see
+// Server.AuditLog. The key lists compare the top-level keys of the decoded
+// config before and after the change and are sorted.
+type AuditEvent struct {
+	Repository string    `json:"repository"`
+	Type       string    `json:"type"` // Repository type, e.g. "s3", identifying where the change came from
+	Time       time.Time `json:"time"`
+	OldHash    string    `json:"old_hash"`
+	NewHash    string    `json:"new_hash"`
+	Added      []string  `json:"added,omitempty"`
+	Removed    []string  `json:"removed,omitempty"`
+	Changed    []string  `json:"changed,omitempty"`
+}
+
+// AuditFile appends audit events to a file as JSON lines. Use its Log method
+// as Server.AuditLog.
+type AuditFile struct {
+	mu   sync.Mutex
+	file *os.File
+	err  error // First error writing an event, returned by Close
+}
+
+// NewAuditFile opens path for appending audit events, creating it if needed.
+func NewAuditFile(path string) (*AuditFile, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditFile{file: file}, nil
+}
+
+// Log appends event to the file. The first write error is returned by Close.
+func (a *AuditFile) Log(event AuditEvent) {
+	line, err := json.Marshal(event)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		_, err = a.file.Write(append(line, '\n'))
+	}
+	if err != nil && a.err == nil {
+		a.err = fmt.Errorf("error writing audit event for %s: %w", event.Repository, err)
+	}
+}
+
+// Close flushes and closes the file. It returns the first error writing an
+// event, if any, so failed audit writes are never lost silently.
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.file.Sync()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	if a.err != nil {
+		return a.err
+	}
+	return err
+}
+
+// recordChange records the per-key hashes of a repository whose content
+// just changed from the previous hash and emits an audit event if AuditLog is
+// set. The hashes are tracked even without AuditLog so the first audited
+// change has a baseline. The initial load is not audited.
+func (s *Server) recordChange(repository source.Repository, previous string) {
+	keys := keyHashes(source.AllData(repository))
+	s.mu.Lock()
+	status, ok := s.repoStatus[repository.GetName()]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	old, hash := status.keyHashes, status.ContentHash
+	status.keyHashes = keys
+	s.mu.Unlock()
+
+	if s.AuditLog == nil || previous == "" {
+		return
+	}
+	event := AuditEvent{
+		Repository: repository.GetName(),
+		Type:       repository.Type(),
+		Time:       time.Now(),
+		OldHash:    previous,
+		NewHash:    hash,
+	}
+	for key, keyHash := range keys {
+		oldHash, existed := old[key]
+		switch {
+		case !existed:
+			event.Added = append(event.Added, key)
+		case oldHash != keyHash:
+			event.Changed = append(event.Changed, key)
+		}
+	}
+	for key := range old {
+		if _, exists := keys[key]; !exists {
+			event.Removed = append(event.Removed, key)
+		}
+	}
+	sort.Strings(event.Added)
+	sort.Strings(event.Removed)
+	sort.Strings(event.Changed)
+	s.AuditLog(event)
+}
+
+// keyHashes hashes each top-level value of data, so changes can be detected
+// per key without keeping a copy of the config.
+func keyHashes(data map[string]interface{}) map[string]string {
+	hashes := make(map[string]string, len(data))
+	for key, value := range data {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded = []byte(fmt.Sprintf("%v", value))
+		}
+		hashes[key] = source.ContentHash(encoded)
+	}
+	return hashes
+}
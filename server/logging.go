@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Supported log formats for SetLogFormat.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// SetLogFormat configures the output format of the logger used by the server,
+// client, and repositories. Valid formats are "text" (the logrus default) and
+// "json", which is suited to log shipping pipelines.
+func SetLogFormat(format string) error {
+	switch strings.ToLower(format) {
+	case LogFormatText, "":
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	case LogFormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("unsupported log format %q, expected %q or %q", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// SetLogLevel configures the minimum level logged by the server, client, and
+// repositories. It accepts any level understood by logrus.ParseLevel, such as
+// "debug", "info", "warn", or "error".
+func SetLogLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("unsupported log level %q: %w", level, err)
+	}
+	logrus.SetLevel(lvl)
+	return nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the request latency histogram.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// otherEndpoint labels requests for paths the server does not serve, so
+// scanners cannot grow the metrics without bound.
+const otherEndpoint = "other"
+
+// Metrics is a point-in-time snapshot of the server's HTTP request metrics.
+type Metrics struct {
+	Endpoints map[string]EndpointMetrics `json:"endpoints"`
+}
+
+// EndpointMetrics holds request metrics for one endpoint.
+type EndpointMetrics struct {
+	Requests    int64         `json:"requests"`
+	StatusCodes map[int]int64 `json:"status_codes"`
+	// LatencyBuckets counts requests by latency in Prometheus style: each
+	// key is an upper bound (e.g. "50ms", or "+Inf") and each count includes
+	// every faster request.
+	LatencyBuckets map[string]int64 `json:"latency_buckets"`
+	LatencySum     time.Duration    `json:"latency_sum_ns"`
+}
+
+// endpointStats accumulates metrics for one endpoint.
+type endpointStats struct {
+	requests    int64
+	statusCodes map[int]int64
+	buckets     []int64 // Non-cumulative counts, one per latencyBuckets entry plus +Inf
+	latencySum  time.Duration
+}
+
+// requestMetrics records HTTP request metrics per endpoint. The zero value is
+// ready to use.
+type requestMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+}
+
+// observe records one request.
+func (m *requestMetrics) observe(endpoint string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.endpoints == nil {
+		m.endpoints = make(map[string]*endpointStats)
+	}
+	stats, ok := m.endpoints[endpoint]
+	if !ok {
+		stats = &endpointStats{
+			statusCodes: make(map[int]int64),
+			buckets:     make([]int64, len(latencyBuckets)+1),
+		}
+		m.endpoints[endpoint] = stats
+	}
+	stats.requests++
+	stats.statusCodes[status]++
+	stats.latencySum += latency
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	stats.buckets[bucket]++
+}
+
+// snapshot returns a copy of the recorded metrics.
+func (m *requestMetrics) snapshot() Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := Metrics{Endpoints: make(map[string]EndpointMetrics, len(m.endpoints))}
+	for endpoint, stats := range m.endpoints {
+		em := EndpointMetrics{
+			Requests:       stats.requests,
+			StatusCodes:    make(map[int]int64, len(stats.statusCodes)),
+			LatencyBuckets: make(map[string]int64, len(stats.buckets)),
+			LatencySum:     stats.latencySum,
+		}
+		for code, n := range stats.statusCodes {
+			em.StatusCodes[code] = n
+		}
+		var cumulative int64
+		for i, n := range stats.buckets {
+			cumulative += n
+			label := "+Inf"
+			if i < len(latencyBuckets) {
+				label = latencyBuckets[i].String()
+			}
+			em.LatencyBuckets[label] = cumulative
+		}
+		out.Endpoints[endpoint] = em
+	}
+	return out
+}
+
+// Metrics returns a snapshot of the HTTP request metrics recorded by servers
+// started with Start or StartWithGracefulShutdown.
+func (s *Server) Metrics() Metrics {
+	return s.metrics.snapshot()
+}
+
+// instrument records the endpoint, status code, and latency of every request
+// handled by next.
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		s.metrics.observe(s.endpointLabel(r.URL.Path), rec.status, time.Since(start))
+	})
+}
+
+// endpointLabel maps a request path to the endpoint it is recorded under.
+func (s *Server) endpointLabel(path string) string {
+	switch path {
+	case "/health", "/ready", "/status", "/metrics", "/version", "/repositories":
+		return path
+	}
+	if strings.HasPrefix(path, "/watch/") {
+		return "/watch/{repo}"
+	}
+	for _, repo := range s.Repositories {
+		switch path {
+		case "/" + repo.GetName(), "/" + repo.GetName() + "/debug", "/" + repo.GetName() + "/history":
+			return path
+		}
+	}
+	return otherEndpoint
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+// ProtobufContentType is the media type of repository responses encoded as
+// a google.protobuf.Struct, see ServeProtobuf.
+const ProtobufContentType = "application/x-protobuf"
+
+// acceptsProtobuf reports whether r lists ProtobufContentType in its Accept
+// header.
+func acceptsProtobuf(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(mediaRange)
+			if err == nil && mediaType == ProtobufContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxExactInteger is the largest integer magnitude a float64, and so a
+// google.protobuf.Struct number, holds exactly (2^53).
+const maxExactInteger = 1 << 53
+
+// encodeProtobuf encodes a decoded configuration map as a
+// google.protobuf.Struct. The map goes through JSON first, as for the /debug
+// endpoint, so values YAML decodes to Go types Struct has no counterpart for
+// (e.g. timestamps) are encoded as their JSON form. Integers a Struct number
+// cannot hold exactly (beyond 2^53) are encoded as their decimal string, as
+// PreserveBigNumbers does for numbers beyond 64 bits.
+func encodeProtobuf(data map[string]interface{}) ([]byte, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+	normalized, err = normalizeNumbers(normalized)
+	if err != nil {
+		return nil, err
+	}
+	message, err := structpb.NewStruct(normalized.(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// normalizeNumbers replaces the json.Numbers in value with float64, or with
+// their string form for integers beyond maxExactInteger.
+func normalizeNumbers(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			normalized, err := normalizeNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalized
+		}
+	case []interface{}:
+		for i, item := range v {
+			normalized, err := normalizeNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+	case json.Number:
+		if n, ok := new(big.Int).SetString(v.String(), 10); ok && n.CmpAbs(big.NewInt(maxExactInteger)) > 0 {
+			return v.String(), nil
+		}
+		return v.Float64()
+	}
+	return value, nil
+}
+
+// decodeRawData decodes the raw data a repository endpoint serves, for
+// responses that encode it as a configuration map. Successive YAML documents
+// are merged, later documents overriding the top-level keys of earlier ones,
+// as repositories do.
+func decodeRawData(rawData []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	decoder := yaml.NewDecoder(bytes.NewReader(rawData))
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range doc {
+			data[key] = value
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sardine-ai/go-remote-config/source"
+)
+
+// DefaultRedactKeys is a starting point for Server.RedactKeys covering
+// common secret names, including the signature and credential parameters of
+// signed cloud storage URLs.
+var DefaultRedactKeys = []string{"*password*", "*secret*", "*token*", "*_key", "*signature*", "*credential*"}
+
+// redactedValue replaces redacted values.
+const redactedValue = "[REDACTED]"
+
+var (
+	// keyValuePattern matches "key=value" and "key: value" pairs in text,
+	// such as URL query parameters and YAML quoted in error messages.
+	// A colon must be followed by a space, so URL schemes are not mistaken
+	// for keys.
+	keyValuePattern = regexp.MustCompile(`([A-Za-z0-9_.-]+)(\s*=\s*|\s*:\s+)("[^"]*"|'[^']*'|[^\s&,;"']+)`)
+	// userinfoPattern matches the password in a URL's userinfo.
+	userinfoPattern = regexp.MustCompile(`(://[^/@\s:]+:)[^/@\s]+@`)
+)
+
+// matchesRedactKey reports whether key matches one of the glob patterns,
+// ignoring case.
+func matchesRedactKey(patterns []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactText masks URL passwords and the values of key/value pairs whose key
+// matches patterns in text, e.g. an error message quoting a signed URL.
+func redactText(patterns []string, text string) string {
+	if len(patterns) == 0 {
+		return text
+	}
+	text = userinfoPattern.ReplaceAllString(text, "${1}"+redactedValue+"@")
+	return keyValuePattern.ReplaceAllStringFunc(text, func(pair string) string {
+		match := keyValuePattern.FindStringSubmatch(pair)
+		if !matchesRedactKey(patterns, match[1]) {
+			return pair
+		}
+		return match[1] + match[2] + redactedValue
+	})
+}
+
+// redactData masks, in place, the values of the keys in data (at any depth)
+// that match patterns.
+func redactData(patterns []string, data interface{}) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if matchesRedactKey(patterns, key) {
+				v[key] = redactedValue
+				continue
+			}
+			redactData(patterns, value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactData(patterns, value)
+		}
+	}
+}
+
+// servedData returns the configuration map /debug and protobuf responses
+// serve for repo: its decoded data, unless its raw data is a SOPS-encrypted
+// document. Then the repository may have decrypted it, and the raw data is
+// decoded instead, so secrets stay encrypted as they do in the raw data.
+func servedData(repo source.Repository) map[string]interface{} {
+	raw, err := decodeRawData(repo.GetRawData())
+	if err == nil {
+		if _, encrypted := raw["sops"].(map[string]interface{}); encrypted {
+			return raw
+		}
+	}
+	return source.AllData(repo)
+}
@@ -4,17 +4,28 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-http-utils/etag"
+	"github.com/go-http-utils/fresh"
+	"github.com/sardine-ai/go-remote-config/schedule"
 	"github.com/sardine-ai/go-remote-config/source"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/singleflight"
 )
 
 // Server serves configuration data over HTTP with automatic refresh.
@@ -25,110 +36,575 @@ type Server struct {
 	AuthKey         string
 	wg              sync.WaitGroup
 
-	// Mutex protects httpServer and repoStatus
-	mu               sync.RWMutex
-	httpServer       *http.Server
-	repoStatus       map[string]*RepositoryStatus
-	shutdownTimeout  time.Duration
+	// AdminAddr, when set, serves the health, readiness, and status endpoints
+	// on a separate listener (e.g. an internal-only interface) and leaves only
+	// the repository endpoints on the address passed to Start.
+	AdminAddr string
+
+	// AuthHeaders are the request headers checked for AuthKey, in order;
+	// the first one present is compared. Set it when a gateway injects the
+	// key under another name, e.g. []string{"X-Api-Key", "X-Auth-Token"}.
+	// Defaults to X-API-KEY.
+	AuthHeaders []string
+
+	// EnableH2C serves HTTP/2 over cleartext (h2c) in addition to HTTP/1.1,
+	// letting clients that poll many repositories multiplex on one connection.
+	EnableH2C bool
+	// MaxHeaderBytes limits the size of request headers. Zero uses the
+	// net/http default (1 MB).
+	MaxHeaderBytes int
+	// IdleTimeout is how long keep-alive connections are kept open between
+	// requests. Zero uses the default of 10 minutes.
+	IdleTimeout time.Duration
+	// DisableKeepAlives closes connections after each request.
+	DisableKeepAlives bool
+	// ShutdownTimeout is how long Shutdown waits for in-flight requests to
+	// drain before giving up. Zero uses the default of 30 seconds; raise it
+	// behind load balancers with long drain windows.
+	ShutdownTimeout time.Duration
+	// MaxInFlightRequests, when positive, limits how many requests each
+	// listener serves at once. Requests over the limit get 503 with a
+	// Retry-After header instead of queuing, so a traffic spike cannot
+	// exhaust memory. /health and /ready are exempt so probes keep working
+	// under load; held /watch requests count against the limit.
+	MaxInFlightRequests int
+
+	// Version identifies the build of the application serving config, e.g.
+	// a release tag or "v1.4.2+abc1234" injected with -ldflags. It is served
+	// by /version with the build information of the binary.
+	Version string
+
+	// ServeConfig registers the repository routes (/{repo-name}, its /debug
+	// and /history endpoints, /watch/{repo} and the /repositories index). NewServer sets it to true;
+	// set it to false for a monitoring-only server, e.g. when clients read
+	// config straight from object storage, so only the health, readiness,
+	// status and metrics endpoints are served and raw config is never
+	// exposed over HTTP.
+	ServeConfig bool
+
+	// PublicRepositoryIndex serves /repositories without the AuthKey check,
+	// so clients can discover the repository names before they have a key.
+	// The repository endpoints themselves stay protected.
+	PublicRepositoryIndex bool
+
+	// ProtectHealthEndpoints keeps repository details off the endpoints
+	// served without AuthKey, for servers exposed publicly. /health and
+	// /ready stay unauthenticated so probes keep working, but /health only
+	// reports the overall status, without the per-repository names and
+	// refresh errors; read those from /status, which requires the key.
+	// /version then requires the key too. The trade-off is that probes and
+	// dashboards reading /health lose the detail.
+	ProtectHealthEndpoints bool
+
+	// ServeProtobuf lets repository endpoints serve the decoded configuration
+	// map as a google.protobuf.Struct (see ProtobufContentType) to requests
+	// that accept it, for clients that prefer a compact binary encoding.
+	// Other requests still get the raw data. A SOPS-encrypted document is
+	// encoded from its raw data, so its values stay encrypted as in the raw
+	// response. Decode responses with client.DecodeProtobuf.
+	ServeProtobuf bool
+
+	// RedactKeys lists glob patterns (matched against keys ignoring case,
+	// e.g. "*password*" or "*_key") whose values are masked in the decoded
+	// config served by /debug, and in refresh errors reported by /status and
+	// /health and logged by the server, where they appear as key=value or
+	// key: value pairs such as the query parameters of a signed URL.
+	// Passwords in URLs are masked too. DefaultRedactKeys covers common
+	// secret names. Empty disables redaction. Errors logged by refreshes
+	// made by the constructor happen before it can be set.
+	RedactKeys []string
+
+	// FallbackRawData maps repository names to baked-in configuration served
+	// by the repository endpoint while the repository has no data (e.g. it
+	// has never loaded successfully). Fallback responses carry the
+	// FallbackHeader header.
+	FallbackRawData map[string][]byte
+
+	// CacheControl is the Cache-Control header sent with repository
+	// responses, letting CDNs and browsers cache config between refreshes,
+	// e.g. "public, max-age=60" for a one-minute refresh interval (use
+	// "private" instead of "public" with AuthKey). Fallback responses and the
+	// health, readiness, status, metrics and watch endpoints are always sent
+	// with "no-store". Empty sends no header on repository responses.
+	CacheControl string
+
+	// FailFastOnStartup makes Start and StartWithGracefulShutdown return
+	// ErrNotReady instead of serving when no repository has loaded
+	// successfully (IsReady is false), so a server with no config is never
+	// deployed.
+	FailFastOnStartup bool
+
+	// AllowPostReads also accepts POST requests with an empty body wherever
+	// GET is accepted, for clients behind gateways that only let POST
+	// through. Other methods are still rejected with 405.
+	AllowPostReads bool
+
+	// BasePath mounts every route under a prefix (e.g. "/config" serves
+	// "/config/health" and "/config/{repo}") for servers behind a shared
+	// ingress. Requests outside the prefix get 404. It applies to the
+	// listeners started by Start; handlers from CreateHandlers are unprefixed
+	// and can be mounted with http.StripPrefix.
+	BasePath string
+
+	// HealthScoreThreshold, when positive, judges repository health by a
+	// smoothed score instead of the last refresh alone: each refresh moves
+	// the score (an exponential moving average of successes) towards 1 on
+	// success or 0 on failure, and the repository is unhealthy while the
+	// score is below the threshold. A single transient failure then no
+	// longer flips the health check. The score is always reported in
+	// RepositoryStatus.
+	HealthScoreThreshold float64
+	// HealthScoreWeight is the weight of the latest refresh in the health
+	// score, between 0 and 1. Zero uses the default of 0.3.
+	HealthScoreWeight float64
+	// UnhealthyAfter, when greater than 1, keeps a repository that has
+	// loaded healthy until that many consecutive refreshes have failed
+	// (RepositoryStatus.ConsecutiveErrors), so isolated failures on a flaky
+	// network do not raise alarms. HealthScoreThreshold takes precedence
+	// when both are set.
+	UnhealthyAfter int
+
+	// CriticalRepositories names the repositories the server cannot work
+	// without. When set, IsHealthy (and /health) only considers these
+	// repositories, so an optional one failing does not fail the health
+	// check, and IsReady (and /ready) requires every one of them to have
+	// loaded at least once instead of any single repository. A name that
+	// matches no repository never counts as loaded or healthy.
+	CriticalRepositories []string
+
+	// OnHealthChange, when set, is called whenever a repository's IsHealthy
+	// flips, with the refresh error that made it unhealthy or nil when it
+	// becomes healthy, so alerts can fire on transitions rather than levels.
+	// It runs on the repository's refresh goroutine after the status is
+	// updated. Refreshes made by the constructor happen before it can be set.
+	OnHealthChange func(repoName string, healthy bool, err error)
+
+	// AuditLog, when set, is called with an AuditEvent whenever a refresh
+	// changes the content a repository serves, e.g. AuditFile.Log to keep a
+	// compliance trail. Refreshes that fetch identical content and the
+	// initial load are not reported. It runs on the repository's refresh
+	// goroutine.
+	AuditLog func(AuditEvent)
+
+	// Mutex protects httpServer, adminServer, repoStatus, watchers, and middleware
+	mu          sync.RWMutex
+	httpServer  *http.Server
+	adminServer *http.Server
+	repoStatus  map[string]*RepositoryStatus
+	watchers    map[string]chan struct{}          // Closed and replaced when a repository's content changes
+	done        <-chan struct{}                   // Closed when the server is stopped
+	middleware  []func(http.Handler) http.Handler // Registered with Use, first is outermost
+
+	// HTTP request metrics, see Metrics
+	metrics requestMetrics
+
+	// Shares a repository refresh between the refresh goroutine and RefreshAll
+	refreshGroup singleflight.Group
 }
 
+// ErrNotReady is returned by Start when FailFastOnStartup is set and no
+// repository has loaded successfully.
+var ErrNotReady = errors.New("no repository loaded successfully")
+
+// FallbackHeader is set to "true" on repository responses served from
+// FallbackRawData.
+const FallbackHeader = "X-Config-Fallback"
+
+const (
+	// defaultShutdownTimeout is how long Shutdown waits for in-flight
+	// requests when ShutdownTimeout is not set.
+	defaultShutdownTimeout = 30 * time.Second
+	// defaultWatchTimeout is how long a /watch request is held when the
+	// client does not specify a timeout.
+	defaultWatchTimeout = 30 * time.Second
+	// maxWatchTimeout caps client-supplied watch timeouts below the
+	// server's write timeout.
+	maxWatchTimeout = 2 * time.Minute
+	// retryAfterSeconds is the Retry-After sent with requests rejected by
+	// MaxInFlightRequests.
+	retryAfterSeconds = "1"
+	// startupRetries is how many times a repository that has never loaded
+	// is retried with backoff before it only follows its schedule.
+	startupRetries = 5
+)
+
+// startupRetryBackoff is the wait before the first startup retry, doubled
+// for each further retry (1s, 2s, 4s, 8s, 16s). It is a variable so tests
+// can shorten it.
+var startupRetryBackoff = time.Second
+
 // RepositoryStatus tracks the health status of a repository.
 type RepositoryStatus struct {
 	Name            string    `json:"name"`
+	Type            string    `json:"type"`
 	LastRefreshTime time.Time `json:"last_refresh_time"`
 	LastRefreshErr  string    `json:"last_refresh_error,omitempty"`
 	RefreshCount    int64     `json:"refresh_count"`
 	RefreshErrors   int64     `json:"refresh_errors"`
-	IsHealthy       bool      `json:"is_healthy"`
+	// ConsecutiveErrors counts the refreshes that failed since the last
+	// successful one.
+	ConsecutiveErrors int64   `json:"consecutive_errors"`
+	IsHealthy         bool    `json:"is_healthy"`
+	HealthScore       float64 `json:"health_score"`
+	ContentHash       string  `json:"content_hash,omitempty"`
+	// Labels are the repository's source.Labels, for grouping repositories
+	// by team, environment and so on.
+	Labels map[string]string `json:"labels,omitempty"`
+	// ServingStale is true while the repository endpoint serves data that
+	// may be out of date: the last good data after a failed refresh, or
+	// FallbackRawData when the repository has no data.
+	ServingStale bool `json:"serving_stale"`
+
+	hasData   bool              // Whether the last successful refresh returned any data
+	keyHashes map[string]string // Hash of each top-level value, to summarize changes for AuditLog
 }
 
+// RepositoryInfo describes a repository in the /repositories index.
+type RepositoryInfo struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Healthy bool   `json:"healthy"`
+}
+
+// RepositoryIndex returns the name, type and health of every repository, in
+// the order they were configured.
+func (s *Server) RepositoryIndex() []RepositoryInfo {
+	statuses := s.GetRepositoryStatus()
+	index := make([]RepositoryInfo, 0, len(s.Repositories))
+	for _, repo := range s.Repositories {
+		info := RepositoryInfo{Name: repo.GetName(), Type: repo.Type()}
+		if status, ok := statuses[repo.GetName()]; ok {
+			info.Healthy = status.IsHealthy
+		}
+		index = append(index, info)
+	}
+	return index
+}
+
+// historyEntry is the JSON form of a source.HistoryEntry served by the
+// history endpoint.
+type historyEntry struct {
+	Time    time.Time `json:"time"`
+	Hash    string    `json:"hash"`
+	RawData string    `json:"raw_data"`
+}
+
+// minRefreshInterval is the shortest refresh interval NewServer accepts.
+const minRefreshInterval = 5 * time.Second
+
 // NewServer creates a new configuration server with the given repositories.
 func NewServer(ctx context.Context, repository []source.Repository, refreshInterval time.Duration) *Server {
-	if refreshInterval < 5*time.Second {
+	return NewServerWithIntervals(ctx, repository, refreshInterval, nil)
+}
+
+// NewServerWithIntervals creates a new configuration server like NewServer,
+// but refreshes the repositories named in intervals at their own interval
+// instead of refreshInterval, e.g. polling a volatile feature-flag file more
+// often than a static routing table. Intervals below 5 seconds are raised to
+// 5 seconds.
+func NewServerWithIntervals(ctx context.Context, repository []source.Repository, refreshInterval time.Duration, intervals map[string]time.Duration) *Server {
+	refreshInterval = clampRefreshInterval(refreshInterval)
+	overrides := make(map[string]time.Duration, len(intervals))
+	for name, interval := range intervals {
+		overrides[name] = clampRefreshInterval(interval)
+	}
+	return newServer(ctx, repository, refreshInterval, intervalSchedulers(refreshInterval, overrides))
+}
+
+// NewServerWithScheduler creates a new configuration server whose repositories
+// are refreshed whenever scheduler fires, instead of at a fixed interval.
+// RefreshInterval is left at zero.
+func NewServerWithScheduler(ctx context.Context, repository []source.Repository, scheduler schedule.Scheduler) *Server {
+	return newServer(ctx, repository, 0, func(source.Repository) schedule.Scheduler {
+		return scheduler
+	})
+}
+
+// clampRefreshInterval raises intervals below minRefreshInterval.
+func clampRefreshInterval(interval time.Duration) time.Duration {
+	if interval < minRefreshInterval {
 		logrus.Warn("refresh interval too low, setting it to 5 seconds")
-		refreshInterval = 5 * time.Second
+		return minRefreshInterval
+	}
+	return interval
+}
+
+// intervalSchedulers returns a scheduler factory that refreshes each
+// repository at its interval in overrides, or at refreshInterval if it has none.
+func intervalSchedulers(refreshInterval time.Duration, overrides map[string]time.Duration) func(source.Repository) schedule.Scheduler {
+	return func(repo source.Repository) schedule.Scheduler {
+		if interval, ok := overrides[repo.GetName()]; ok {
+			return schedule.Every(interval)
+		}
+		return schedule.Every(refreshInterval)
 	}
+}
+
+// newServer builds the server, performs the initial refresh and starts one
+// refresh goroutine per repository, driven by the scheduler schedulerFor
+// returns for it.
+func newServer(ctx context.Context, repository []source.Repository, refreshInterval time.Duration, schedulerFor func(source.Repository) schedule.Scheduler) *Server {
 	ctx, cancel := context.WithCancel(ctx)
 	server := &Server{
 		Repositories:    repository,
 		RefreshInterval: refreshInterval,
+		ServeConfig:     true,
 		cancel:          cancel,
 		repoStatus:      make(map[string]*RepositoryStatus),
-		shutdownTimeout: 30 * time.Second,
+		watchers:        make(map[string]chan struct{}),
+		done:            ctx.Done(),
 	}
 
 	// Initialize status tracking for each repository
 	for _, repo := range server.Repositories {
 		server.repoStatus[repo.GetName()] = &RepositoryStatus{
-			Name: repo.GetName(),
+			Name:   repo.GetName(),
+			Type:   repo.Type(),
+			Labels: maps.Clone(source.LabelsOf(repo)),
 		}
+		server.watchers[repo.GetName()] = make(chan struct{})
 	}
 
 	// Initial refresh
 	for _, repo := range server.Repositories {
-		err := repo.Refresh()
-		if err != nil {
-			logrus.WithError(err).WithField("repository", repo.GetName()).Error("error refreshing repository")
-			server.recordRefreshError(repo.GetName(), err)
-		} else {
-			server.recordRefreshSuccess(repo.GetName())
-		}
+		server.refreshRepository(repo)
 	}
 
 	// Start background refresh goroutines
 	for _, repo := range server.Repositories {
 		server.wg.Add(1)
-		go server.refresh(ctx, repo, refreshInterval)
+		go server.refresh(ctx, repo, schedulerFor(repo))
 	}
 	return server
 }
 
-// refresh periodically refreshes a repository and tracks its status.
-func (s *Server) refresh(ctx context.Context, repository source.Repository, refreshInterval time.Duration) {
+// refresh refreshes a repository whenever the scheduler fires and tracks its status.
+func (s *Server) refresh(ctx context.Context, repository source.Repository, scheduler schedule.Scheduler) {
 	defer s.wg.Done()
-	ticker := time.NewTicker(refreshInterval)
-	defer ticker.Stop()
+	schedule.Run(ctx, s.withStartupRetry(repository.GetName(), scheduler), func() {
+		s.refreshRepository(repository)
+	})
+}
 
-	for {
+// withStartupRetry wraps scheduler so a repository whose initial refresh
+// failed is retried with exponential backoff, up to startupRetries times,
+// instead of staying unready until its next scheduled refresh. Retries never
+// delay a scheduled refresh, and stop for good once the repository loads.
+func (s *Server) withStartupRetry(name string, scheduler schedule.Scheduler) schedule.Scheduler {
+	retries := 0
+	return schedule.SchedulerFunc(func(now time.Time) time.Time {
+		next := scheduler.Next(now)
+		if retries >= startupRetries || s.hasLoaded(name) {
+			retries = startupRetries
+			return next
+		}
+		retry := now.Add(startupRetryBackoff << retries)
+		retries++
+		if next.IsZero() || retry.Before(next) {
+			return retry
+		}
+		return next
+	})
+}
+
+// hasLoaded reports whether a repository has refreshed successfully at least once.
+func (s *Server) hasLoaded(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok := s.repoStatus[name]
+	return ok && status.RefreshCount > 0
+}
+
+// RefreshAll refreshes every repository now, concurrently, and returns the
+// outcome of each by name, nil for repositories that refreshed successfully.
+// Results are recorded as for scheduled refreshes. A repository already
+// being refreshed is not fetched again: its refresh in progress is waited
+// for and its result reported. If ctx ends first, the repositories still
+// refreshing report ctx.Err() and finish in the background.
+func (s *Server) RefreshAll(ctx context.Context) map[string]error {
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(s.Repositories))
+	for _, repo := range s.Repositories {
+		go func(repo source.Repository) {
+			results <- result{repo.GetName(), s.refreshRepository(repo)}
+		}(repo)
+	}
+
+	errs := make(map[string]error, len(s.Repositories))
+	for range s.Repositories {
 		select {
-		case <-ticker.C:
-			err := repository.Refresh()
-			if err != nil {
-				logrus.WithError(err).WithField("repository", repository.GetName()).Error("error refreshing repository")
-				s.recordRefreshError(repository.GetName(), err)
-			} else {
-				s.recordRefreshSuccess(repository.GetName())
-			}
+		case r := <-results:
+			errs[r.name] = r.err
 		case <-ctx.Done():
-			return
+			for _, repo := range s.Repositories {
+				if _, ok := errs[repo.GetName()]; !ok {
+					errs[repo.GetName()] = ctx.Err()
+				}
+			}
+			return errs
 		}
 	}
+	return errs
 }
 
-// recordRefreshSuccess records a successful refresh for a repository.
-func (s *Server) recordRefreshSuccess(name string) {
+// refreshRepository refreshes a repository once, records the outcome and
+// returns the refresh error. Concurrent calls for the same repository share
+// a single refresh.
+func (s *Server) refreshRepository(repository source.Repository) error {
+	_, err, _ := s.refreshGroup.Do(repository.GetName(), func() (interface{}, error) {
+		err := source.SafeRefresh(repository)
+		if err != nil {
+			logrus.WithError(s.redactErr(err)).WithField("repository", repository.GetName()).Error("error refreshing repository")
+			s.recordRefreshError(repository.GetName(), err)
+			return nil, err
+		}
+		s.recordRefreshSuccess(repository.GetName())
+		if previous, changed := s.recordContent(repository.GetName(), repository.GetRawData()); changed {
+			s.recordChange(repository, previous)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// recordContent updates the content hash of a repository and wakes any
+// /watch requests waiting on it if the content changed. It returns the
+// previous hash and whether the content changed.
+func (s *Server) recordContent(name string, rawData []byte) (previous string, changed bool) {
+	hash := source.ContentHash(rawData)
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if status, ok := s.repoStatus[name]; ok {
-		status.LastRefreshTime = time.Now()
-		status.LastRefreshErr = ""
-		status.RefreshCount++
-		status.IsHealthy = true
+	status, ok := s.repoStatus[name]
+	if !ok {
+		return "", false
+	}
+	status.hasData = len(rawData) > 0
+	if status.ContentHash == hash {
+		return status.ContentHash, false
+	}
+	previous = status.ContentHash
+	status.ContentHash = hash
+	close(s.watchers[name])
+	s.watchers[name] = make(chan struct{})
+	return previous, true
+}
+
+// watchState returns the current content hash of a repository and a channel
+// that is closed when the content next changes.
+func (s *Server) watchState(name string) (hash string, changed <-chan struct{}, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok := s.repoStatus[name]
+	if !ok {
+		return "", nil, false
+	}
+	return status.ContentHash, s.watchers[name], true
+}
+
+// recordRefreshSuccess records a successful refresh for a repository. It
+// does not depend on the content: a repository that recovers with the same
+// data it served before failing clears its error and, unless the health
+// score is still below HealthScoreThreshold, becomes healthy again.
+func (s *Server) recordRefreshSuccess(name string) {
+	s.mu.Lock()
+	status, ok := s.repoStatus[name]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	status.LastRefreshTime = time.Now()
+	status.LastRefreshErr = ""
+	status.RefreshCount++
+	status.ConsecutiveErrors = 0
+	changed := s.updateHealth(status, true)
+	healthy := status.IsHealthy
+	s.mu.Unlock()
+
+	if changed {
+		s.notifyHealthChange(name, healthy, nil)
 	}
 }
 
 // recordRefreshError records a failed refresh for a repository.
 func (s *Server) recordRefreshError(name string, err error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if status, ok := s.repoStatus[name]; ok {
-		status.LastRefreshErr = err.Error()
-		status.RefreshErrors++
-		status.IsHealthy = false
+	status, ok := s.repoStatus[name]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	status.LastRefreshErr = err.Error()
+	status.RefreshErrors++
+	status.ConsecutiveErrors++
+	changed := s.updateHealth(status, false)
+	healthy := status.IsHealthy
+	s.mu.Unlock()
+
+	if changed {
+		s.notifyHealthChange(name, healthy, err)
+	}
+}
+
+// redactErr returns err with its message redacted by RedactKeys, for logging.
+func (s *Server) redactErr(err error) error {
+	if len(s.RedactKeys) == 0 {
+		return err
 	}
+	return errors.New(redactText(s.RedactKeys, err.Error()))
 }
 
-// GetRepositoryStatus returns the status of all repositories.
+// notifyHealthChange logs a health transition and calls OnHealthChange. err
+// is only passed on for transitions to unhealthy.
+func (s *Server) notifyHealthChange(name string, healthy bool, err error) {
+	if healthy {
+		err = nil
+		logrus.WithField("repository", name).Info("repository became healthy")
+	} else {
+		logrus.WithError(s.redactErr(err)).WithField("repository", name).Warn("repository became unhealthy")
+	}
+	if s.OnHealthChange != nil {
+		s.OnHealthChange(name, healthy, err)
+	}
+}
+
+// defaultHealthScoreWeight is the weight of the latest refresh in the health
+// score when HealthScoreWeight is not set.
+const defaultHealthScoreWeight = 0.3
+
+// updateHealth folds a refresh outcome into the repository's health score and
+// recomputes IsHealthy, reporting whether it changed. The caller must hold s.mu.
+func (s *Server) updateHealth(status *RepositoryStatus, success bool) bool {
+	wasHealthy := status.IsHealthy
+	outcome := 0.0
+	if success {
+		outcome = 1.0
+	}
+	if status.RefreshCount+status.RefreshErrors == 1 {
+		// The first refresh seeds the average
+		status.HealthScore = outcome
+	} else {
+		weight := s.HealthScoreWeight
+		if weight <= 0 || weight > 1 {
+			weight = defaultHealthScoreWeight
+		}
+		status.HealthScore = weight*outcome + (1-weight)*status.HealthScore
+	}
+
+	if s.HealthScoreThreshold > 0 {
+		status.IsHealthy = status.RefreshCount > 0 && status.HealthScore >= s.HealthScoreThreshold
+	} else {
+		// A single failure is enough by default
+		unhealthyAfter := int64(max(s.UnhealthyAfter, 1))
+		status.IsHealthy = status.RefreshCount > 0 && status.ConsecutiveErrors < unhealthyAfter
+	}
+	return status.IsHealthy != wasHealthy
+}
+
+// GetRepositoryStatus returns the status of all repositories, with refresh
+// errors redacted by RedactKeys.
 func (s *Server) GetRepositoryStatus() map[string]*RepositoryStatus {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -137,15 +613,118 @@ func (s *Server) GetRepositoryStatus() map[string]*RepositoryStatus {
 	result := make(map[string]*RepositoryStatus)
 	for k, v := range s.repoStatus {
 		statusCopy := *v
+		statusCopy.LastRefreshErr = redactText(s.RedactKeys, v.LastRefreshErr)
+		if v.hasData {
+			statusCopy.ServingStale = statusCopy.LastRefreshErr != ""
+		} else {
+			_, statusCopy.ServingStale = s.FallbackRawData[k]
+		}
 		result[k] = &statusCopy
 	}
 	return result
 }
 
-// IsHealthy returns true if all repositories are healthy.
+// IsDegraded returns true if any repository endpoint is serving stale or
+// fallback data (see RepositoryStatus.ServingStale). A degraded server can
+// still be healthy: it is up and serving config, just not fresh config.
+func (s *Server) IsDegraded() bool {
+	return isDegraded(s.GetRepositoryStatus())
+}
+
+// isDegraded reports whether any of statuses is serving stale data.
+func isDegraded(statuses map[string]*RepositoryStatus) bool {
+	for _, status := range statuses {
+		if status.ServingStale {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRepositoryStatus returns the statuses matching the /status query
+// filters: "healthy" or "unhealthy" (booleans) select by health, "name"
+// keeps repositories whose name contains the given substring, and each
+// "label" ("key:value") keeps repositories with that label.
+func filterRepositoryStatus(statuses map[string]*RepositoryStatus, query url.Values) (map[string]*RepositoryStatus, error) {
+	var wantHealthy *bool
+	for _, param := range []string{"healthy", "unhealthy"} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s filter %q", param, v)
+		}
+		if param == "unhealthy" {
+			b = !b
+		}
+		if wantHealthy != nil && *wantHealthy != b {
+			return nil, errors.New("conflicting healthy and unhealthy filters")
+		}
+		wantHealthy = &b
+	}
+	name := query.Get("name")
+	labels := make(map[string]string)
+	for _, v := range query["label"] {
+		key, value, ok := strings.Cut(v, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label filter %q, expected key:value", v)
+		}
+		labels[key] = value
+	}
+
+	result := make(map[string]*RepositoryStatus)
+	for k, status := range statuses {
+		if wantHealthy != nil && status.IsHealthy != *wantHealthy {
+			continue
+		}
+		if name != "" && !strings.Contains(status.Name, name) {
+			continue
+		}
+		if !hasLabels(status.Labels, labels) {
+			continue
+		}
+		result[k] = status
+	}
+	return result, nil
+}
+
+// hasLabels reports whether labels contains every key/value pair of want.
+func hasLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Dump returns the decoded configuration map of the named repository, after
+// any processing the repository applies on refresh (such as YAML merge keys).
+// It returns false if no repository has that name.
+func (s *Server) Dump(name string) (map[string]interface{}, bool) {
+	for _, repo := range s.Repositories {
+		if repo.GetName() == name {
+			return source.AllData(repo), true
+		}
+	}
+	return nil, false
+}
+
+// IsHealthy returns true if all repositories are healthy, or all of
+// CriticalRepositories when it is set.
 func (s *Server) IsHealthy() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	if len(s.CriticalRepositories) > 0 {
+		for _, name := range s.CriticalRepositories {
+			if status, ok := s.repoStatus[name]; !ok || !status.IsHealthy {
+				return false
+			}
+		}
+		return true
+	}
 	for _, status := range s.repoStatus {
 		if !status.IsHealthy {
 			return false
@@ -154,10 +733,20 @@ func (s *Server) IsHealthy() bool {
 	return len(s.repoStatus) > 0
 }
 
-// IsReady returns true if at least one repository has been successfully refreshed.
+// IsReady returns true if at least one repository has been successfully
+// refreshed, or, when CriticalRepositories is set, if every critical
+// repository has.
 func (s *Server) IsReady() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	if len(s.CriticalRepositories) > 0 {
+		for _, name := range s.CriticalRepositories {
+			if status, ok := s.repoStatus[name]; !ok || status.RefreshCount == 0 {
+				return false
+			}
+		}
+		return true
+	}
 	for _, status := range s.repoStatus {
 		if status.RefreshCount > 0 && status.IsHealthy {
 			return true
@@ -173,45 +762,209 @@ func (s *Server) Stop() {
 }
 
 // Start starts the HTTP server and blocks until it's stopped.
-// Returns an error if the server fails to start.
+// Returns an error if the server fails to start, or ErrNotReady if
+// FailFastOnStartup is set and no repository has loaded.
+// If AdminAddr is set, the admin endpoints are served on that address and
+// Start blocks until both listeners have stopped.
 // Use StartWithGracefulShutdown for production deployments.
 func (s *Server) Start(addr string) error {
+	if s.FailFastOnStartup && !s.IsReady() {
+		logrus.Error("refusing to start server: no repository loaded successfully")
+		return ErrNotReady
+	}
 	logrus.Info("Starting server on ", addr)
 
 	handlers := s.CreateHandlers()
-	handler := etag.Handler(handlers, false)
-	if s.AuthKey != "" {
-		handler = Auth(handler, s.AuthKey)
+	if s.AdminAddr != "" {
+		handlers = s.CreateConfigHandlers()
 	}
+	httpServer := s.newHTTPServer(addr, handlers)
 
-	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		ReadTimeout:  3 * time.Minute,
-		WriteTimeout: 3 * time.Minute,
-		IdleTimeout:  10 * time.Minute,
+	var adminServer *http.Server
+	if s.AdminAddr != "" {
+		logrus.Info("Starting admin server on ", s.AdminAddr)
+		adminServer = s.newHTTPServer(s.AdminAddr, s.CreateAdminHandlers())
 	}
 
-	// Store the server reference with proper locking
+	// Store the server references with proper locking
 	s.mu.Lock()
 	s.httpServer = httpServer
+	s.adminServer = adminServer
 	s.mu.Unlock()
 
-	err := httpServer.ListenAndServe()
-	if err != nil && err != http.ErrServerClosed {
-		logrus.WithError(err).Error("error starting server")
-		return fmt.Errorf("server failed to start: %w", err)
+	listeners := []*http.Server{httpServer}
+	if adminServer != nil {
+		listeners = append(listeners, adminServer)
+	}
+
+	errChan := make(chan error, len(listeners))
+	for _, srv := range listeners {
+		go func(srv *http.Server) {
+			err := srv.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logrus.WithError(err).WithField("addr", srv.Addr).Error("error starting server")
+				errChan <- fmt.Errorf("server failed to start: %w", err)
+				return
+			}
+			errChan <- nil
+		}(srv)
+	}
+
+	for range listeners {
+		if err := <-errChan; err != nil {
+			// Close the remaining listener so Start does not block forever
+			for _, srv := range listeners {
+				_ = srv.Close()
+			}
+			return err
+		}
 	}
 	return nil
 }
 
+// Handler returns every endpoint of the server (as CreateHandlers) wrapped in
+// the same middleware as the listeners started by Start: ETags, AuthKey
+// checks, MaxInFlightRequests, request metrics and BasePath. Use it to mount
+// the server in an existing http.Server or router; EnableH2C and the
+// listener settings (timeouts, keep-alives) are left to the embedder.
+func (s *Server) Handler() http.Handler {
+	return s.compose(s.CreateHandlers())
+}
+
+// compose wraps handler with the etag, auth, in-flight limit, metrics and
+// base path middleware.
+func (s *Server) compose(handler http.Handler) http.Handler {
+	handler = s.etagHandler(handler)
+	if s.AuthKey != "" {
+		handler = auth(handler, s.AuthKey, s.AuthHeaders, s.publicPath)
+	}
+	if s.MaxInFlightRequests > 0 {
+		handler = limitInFlight(handler, s.MaxInFlightRequests)
+	}
+	handler = s.instrument(handler)
+	if s.BasePath != "" {
+		handler = withBasePath(s.BasePath, handler)
+	}
+	return handler
+}
+
+// newHTTPServer composes handler with the server's middleware and builds an
+// http.Server listening on addr.
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	handler = s.compose(handler)
+	if s.EnableH2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	idleTimeout := 10 * time.Minute
+	if s.IdleTimeout > 0 {
+		idleTimeout = s.IdleTimeout
+	}
+
+	httpServer := &http.Server{
+		Addr:           addr,
+		Handler:        handler,
+		ReadTimeout:    3 * time.Minute,
+		WriteTimeout:   3 * time.Minute,
+		IdleTimeout:    idleTimeout,
+		MaxHeaderBytes: s.MaxHeaderBytes,
+	}
+	if s.DisableKeepAlives {
+		httpServer.SetKeepAlivesEnabled(false)
+	}
+	return httpServer
+}
+
+// withBasePath serves next under prefix, stripping it from request paths.
+// Requests outside the prefix get 404.
+func withBasePath(prefix string, next http.Handler) http.Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	strip := http.StripPrefix(prefix, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		strip.ServeHTTP(w, r)
+	})
+}
+
+// limitInFlight serves at most limit requests to next at once, rejecting the
+// rest with 503. Health and readiness probes bypass the limit.
+func limitInFlight(next http.Handler, limit int) http.Handler {
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health", "/ready":
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			http.Error(w, "too many requests in flight", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// isReadRequest reports whether r uses a method the read-only endpoints accept:
+// GET and HEAD, plus POST with an empty body when AllowPostReads is set.
+func (s *Server) isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return s.AllowPostReads && r.ContentLength == 0
+	default:
+		return false
+	}
+}
+
+// etagHandler adds ETags to responses. The etag package buffers and hashes
+// every response body, which is wasteful for large configuration files, so
+// repository endpoints bypass it and set an ETag from the content hash
+// recorded at refresh time instead.
+func (s *Server) etagHandler(next http.Handler) http.Handler {
+	buffered := etag.Handler(next, false)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isRepositoryPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buffered.ServeHTTP(w, r)
+	})
+}
+
+// isRepositoryPath reports whether path is a repository's raw data endpoint.
+func (s *Server) isRepositoryPath(path string) bool {
+	for _, repo := range s.Repositories {
+		if path == "/"+repo.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
 // StartWithGracefulShutdown starts the server and handles OS signals for graceful shutdown.
 // This is the recommended way to run the server in production.
 // It blocks until the server is stopped via SIGINT or SIGTERM.
+// On Unix systems, SIGUSR1 logs the status of every repository without
+// stopping the server (e.g. `kill -USR1 <pid>`).
 func (s *Server) StartWithGracefulShutdown(addr string) error {
 	// Channel to receive OS signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	// Channel to receive status dump signals
+	dumpChan := make(chan os.Signal, 1)
+	if len(statusDumpSignals) > 0 {
+		signal.Notify(dumpChan, statusDumpSignals...)
+		defer signal.Stop(dumpChan)
+	}
 
 	// Channel to receive server errors
 	errChan := make(chan error, 1)
@@ -224,25 +977,61 @@ func (s *Server) StartWithGracefulShutdown(addr string) error {
 	}()
 
 	// Wait for signal or error
-	select {
-	case sig := <-sigChan:
-		logrus.WithField("signal", sig).Info("Received shutdown signal, initiating graceful shutdown")
-	case err := <-errChan:
-		return err
+	for {
+		select {
+		case <-dumpChan:
+			s.logRepositoryStatus()
+		case sig := <-sigChan:
+			logrus.WithField("signal", sig).Info("Received shutdown signal, initiating graceful shutdown")
+			// Graceful shutdown
+			return s.Shutdown()
+		case err := <-errChan:
+			return err
+		}
 	}
+}
 
-	// Graceful shutdown
-	return s.Shutdown()
+// logRepositoryStatus logs the overall health and the status of every
+// repository, one entry per repository in name order.
+func (s *Server) logRepositoryStatus() {
+	statuses := s.GetRepositoryStatus()
+	names := make([]string, 0, len(statuses))
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	logrus.WithFields(logrus.Fields{
+		"healthy":      s.IsHealthy(),
+		"ready":        s.IsReady(),
+		"repositories": len(names),
+	}).Info("Server status")
+	for _, name := range names {
+		status := statuses[name]
+		logrus.WithFields(logrus.Fields{
+			"repository":         status.Name,
+			"type":               status.Type,
+			"is_healthy":         status.IsHealthy,
+			"last_refresh_time":  status.LastRefreshTime,
+			"last_refresh_error": status.LastRefreshErr,
+			"refresh_count":      status.RefreshCount,
+			"refresh_errors":     status.RefreshErrors,
+			"content_hash":       status.ContentHash,
+		}).Info("Repository status")
+	}
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server. The main and admin listeners
+// are shut down together, each within ShutdownTimeout, and an error from
+// either is returned without leaving the other running.
 func (s *Server) Shutdown() error {
 	// Stop refresh goroutines first
 	s.Stop()
 
-	// Get the HTTP server with proper locking
+	// Get the HTTP servers with proper locking
 	s.mu.RLock()
 	httpServer := s.httpServer
+	adminServer := s.adminServer
 	s.mu.RUnlock()
 
 	if httpServer == nil {
@@ -250,53 +1039,132 @@ func (s *Server) Shutdown() error {
 	}
 
 	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	shutdownTimeout := defaultShutdownTimeout
+	if s.ShutdownTimeout > 0 {
+		shutdownTimeout = s.ShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	var adminErr error
+	var wg sync.WaitGroup
+	if adminServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logrus.Info("Shutting down admin HTTP server...")
+			if err := adminServer.Shutdown(ctx); err != nil {
+				logrus.WithError(err).Error("Error during admin server shutdown")
+				adminErr = fmt.Errorf("admin server shutdown failed: %w", err)
+			}
+		}()
+	}
+
 	logrus.Info("Shutting down HTTP server...")
-	if err := httpServer.Shutdown(ctx); err != nil {
-		logrus.WithError(err).Error("Error during server shutdown")
-		return fmt.Errorf("server shutdown failed: %w", err)
+	var err error
+	if shutdownErr := httpServer.Shutdown(ctx); shutdownErr != nil {
+		logrus.WithError(shutdownErr).Error("Error during server shutdown")
+		err = fmt.Errorf("server shutdown failed: %w", shutdownErr)
+	}
+	wg.Wait()
+	if err := errors.Join(err, adminErr); err != nil {
+		return err
 	}
 
 	logrus.Info("Server shutdown complete")
 	return nil
 }
 
+// Use registers middleware (tracing, tenant routing, extra auth checks, ...)
+// around the server's routes. Middleware is applied in registration order,
+// the first registered being the outermost, by CreateHandlers,
+// CreateAdminHandlers and CreateConfigHandlers, so it must be registered
+// before Start or those calls. On the listeners started by Start it runs
+// inside the server's own middleware: after BasePath stripping, request
+// metrics, MaxInFlightRequests and AuthKey checks (so it only sees
+// authenticated requests), and inside the ETag handler, so headers it sets
+// are sent with 304 responses too.
+func (s *Server) Use(mw func(http.Handler) http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.middleware = append(s.middleware, mw)
+}
+
+// withMiddleware wraps handler in the middleware registered with Use.
+func (s *Server) withMiddleware(handler http.Handler) http.Handler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	return handler
+}
+
 // CreateHandlers creates the HTTP handlers including health and readiness endpoints.
 func (s *Server) CreateHandlers() http.Handler {
 	mux := http.NewServeMux()
+	s.registerAdminHandlers(mux)
+	s.registerConfigHandlers(mux)
+	return s.withMiddleware(mux)
+}
+
+// CreateAdminHandlers creates the HTTP handlers for the health, readiness, and
+// status endpoints only. It is used for the AdminAddr listener.
+func (s *Server) CreateAdminHandlers() http.Handler {
+	mux := http.NewServeMux()
+	s.registerAdminHandlers(mux)
+	return s.withMiddleware(mux)
+}
+
+// CreateConfigHandlers creates the HTTP handlers for the repository endpoints only.
+func (s *Server) CreateConfigHandlers() http.Handler {
+	mux := http.NewServeMux()
+	s.registerConfigHandlers(mux)
+	return s.withMiddleware(mux)
+}
 
+// registerAdminHandlers registers the health, readiness, and status endpoints on mux.
+func (s *Server) registerAdminHandlers(mux *http.ServeMux) {
 	// Health endpoint - returns 200 if server is running and all repos are healthy
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" && r.Method != "HEAD" {
+		if !s.isReadRequest(r) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
+		w.Header().Set("Cache-Control", "no-store")
 		w.Header().Set("Content-Type", "application/json")
+		repositories := s.GetRepositoryStatus()
+		degraded := isDegraded(repositories)
+		body := map[string]interface{}{
+			"status":       "unhealthy",
+			"degraded":     degraded,
+			"repositories": repositories,
+		}
+		if s.ProtectHealthEndpoints {
+			delete(body, "repositories")
+		}
 		if s.IsHealthy() {
+			body["status"] = "healthy"
+			if degraded {
+				// Up and serving, but not fresh config
+				body["status"] = "degraded"
+			}
 			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":       "healthy",
-				"repositories": s.GetRepositoryStatus(),
-			})
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":       "unhealthy",
-				"repositories": s.GetRepositoryStatus(),
-			})
 		}
+		json.NewEncoder(w).Encode(body)
 	})
 
 	// Readiness endpoint - returns 200 if at least one repo has been refreshed
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" && r.Method != "HEAD" {
+		if !s.isReadRequest(r) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
+		w.Header().Set("Cache-Control", "no-store")
 		w.Header().Set("Content-Type", "application/json")
 		if s.IsReady() {
 			w.WriteHeader(http.StatusOK)
@@ -307,48 +1175,280 @@ func (s *Server) CreateHandlers() http.Handler {
 		}
 	})
 
-	// Status endpoint - detailed status of all repositories
+	// Status endpoint - detailed status of all repositories, optionally
+	// filtered with ?healthy=true|false (or ?unhealthy=true) and ?name=substring
 	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" && r.Method != "HEAD" {
+		if !s.isReadRequest(r) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
+		statuses := s.GetRepositoryStatus()
+		repositories, err := filterRepositoryStatus(statuses, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"healthy":      s.IsHealthy(),
 			"ready":        s.IsReady(),
-			"repositories": s.GetRepositoryStatus(),
+			"degraded":     isDegraded(statuses),
+			"repositories": repositories,
 		})
 	})
+	// Metrics endpoint - per-endpoint request counts, status codes, and latency
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !s.isReadRequest(r) {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Metrics())
+	})
+
+	// Version endpoint - the build of the running server, see BuildInfo
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		if !s.isReadRequest(r) {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.BuildInfo())
+	})
+}
+
+// registerConfigHandlers registers one endpoint per repository on mux,
+// unless ServeConfig is false.
+func (s *Server) registerConfigHandlers(mux *http.ServeMux) {
+	if !s.ServeConfig {
+		return
+	}
+
+	// Repository index - the configured repositories, for discovery
+	mux.HandleFunc("/repositories", func(w http.ResponseWriter, r *http.Request) {
+		if !s.isReadRequest(r) {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.RepositoryIndex())
+	})
+
+	// Watch endpoint - long-polls until the repository's content hash differs
+	// from the "hash" query parameter. Responds 200 with the new hash on change
+	// and 304 when the timeout expires first.
+	mux.HandleFunc("/watch/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		if !s.isReadRequest(r) {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		timeout := defaultWatchTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "Invalid timeout", http.StatusBadRequest)
+				return
+			}
+			timeout = min(d, maxWatchTimeout)
+		}
+
+		name := r.PathValue("repo")
+		hash, changed, ok := s.watchState(name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if fallback, ok := s.FallbackRawData[name]; ok && hash == "" {
+			// Match the ETag of the fallback the repository endpoint serves
+			// until the repository loads
+			hash = source.ContentHash(fallback)
+		}
+
+		if r.URL.Query().Get("hash") == hash {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-changed:
+				hash, _, _ = s.watchState(name)
+			case <-timer.C:
+				w.WriteHeader(http.StatusNotModified)
+				return
+			case <-s.done:
+				w.WriteHeader(http.StatusNotModified)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"hash": hash})
+	})
 
 	// Repository endpoints
 	for _, repo := range s.Repositories {
 		mux.HandleFunc("/"+repo.GetName(), func(w http.ResponseWriter, r *http.Request) {
-			if r.Method != "GET" && r.Method != "HEAD" {
+			if !s.isReadRequest(r) {
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				return
 			}
+			// Read the hash before the data: the hash is recorded after the
+			// data is swapped in, so an ETag can lag the body but never lead
+			// it, and a lagging ETag only costs the client one extra fetch.
+			hash, _, _ := s.watchState(repo.GetName())
 			response := repo.GetRawData()
+			isFallback := false
+			if len(response) == 0 {
+				if fallback, ok := s.FallbackRawData[repo.GetName()]; ok {
+					w.Header().Set(FallbackHeader, "true")
+					w.Header().Set("Cache-Control", "no-store")
+					response = fallback
+					hash = source.ContentHash(fallback)
+					isFallback = true
+				}
+			}
+			if s.CacheControl != "" && w.Header().Get("Cache-Control") == "" {
+				w.Header().Set("Cache-Control", s.CacheControl)
+			}
+			protobuf := s.ServeProtobuf && acceptsProtobuf(r)
+			if s.ServeProtobuf {
+				w.Header().Add("Vary", "Accept")
+			}
+			if protobuf {
+				// Keep caches from serving one encoding for the other
+				hash += "-pb"
+			}
+			if len(response) > 0 {
+				w.Header().Set("ETag", `"`+hash+`"`)
+				if fresh.IsFresh(r.Header, w.Header()) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+			if protobuf && len(response) > 0 {
+				data := servedData(repo)
+				var err error
+				if isFallback {
+					data, err = decodeRawData(response)
+				}
+				if err == nil {
+					response, err = encodeProtobuf(data)
+				}
+				if err != nil {
+					logrus.WithError(err).WithField("repository", repo.GetName()).Error("error encoding protobuf response")
+					http.Error(w, "error encoding config", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", ProtobufContentType)
+			}
 			_, err := w.Write(response)
 			if err != nil {
 				logrus.WithError(err).Error("error writing response")
 			}
 		})
+
+		// Debug endpoint - the decoded configuration map as pretty JSON
+		mux.HandleFunc("/"+repo.GetName()+"/debug", func(w http.ResponseWriter, r *http.Request) {
+			if !s.isReadRequest(r) {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			data := servedData(repo)
+			redactData(s.RedactKeys, data)
+			w.Header().Set("Content-Type", "application/json")
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(data); err != nil {
+				logrus.WithError(err).Error("error writing response")
+			}
+		})
+
+		// History endpoint - recent payloads of repositories that record them
+		if historian, ok := repo.(interface{ History() []source.HistoryEntry }); ok {
+			mux.HandleFunc("/"+repo.GetName()+"/history", func(w http.ResponseWriter, r *http.Request) {
+				if !s.isReadRequest(r) {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				entries := historian.History()
+				history := make([]historyEntry, 0, len(entries))
+				for i := len(entries) - 1; i >= 0; i-- {
+					history = append(history, historyEntry{
+						Time:    entries[i].Time,
+						Hash:    entries[i].Hash,
+						RawData: string(entries[i].RawData),
+					})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				encoder := json.NewEncoder(w)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(history); err != nil {
+					logrus.WithError(err).Error("error writing response")
+				}
+			})
+		}
+	}
+}
+
+// defaultAuthHeader is the header Auth checks when no header names are given.
+const defaultAuthHeader = "X-API-KEY"
+
+// Auth requires requests to carry authKey in the first of headerNames that
+// is present, X-API-KEY if none are given. /health, /ready and /version are
+// served without a key.
+func Auth(next http.Handler, authKey string, headerNames ...string) http.Handler {
+	return auth(next, authKey, headerNames, isPublicPath)
+}
+
+// isPublicPath reports whether Auth serves path without a key: the health
+// check endpoints (needed for K8s probes) and the build version, which
+// operators check without credentials.
+func isPublicPath(path string) bool {
+	return path == "/health" || path == "/ready" || path == "/version"
+}
+
+// publicPath reports whether the server's auth serves path without AuthKey:
+// the paths isPublicPath allows, adjusted by ProtectHealthEndpoints and
+// PublicRepositoryIndex.
+func (s *Server) publicPath(path string) bool {
+	switch path {
+	case "/version":
+		return !s.ProtectHealthEndpoints
+	case "/repositories":
+		return s.PublicRepositoryIndex
 	}
-	return mux
+	return isPublicPath(path)
 }
 
-func Auth(next http.Handler, authKey string) http.Handler {
+// auth is Auth with the paths served without a key decided by public.
+func auth(next http.Handler, authKey string, headerNames []string, public func(path string) bool) http.Handler {
+	if len(headerNames) == 0 {
+		headerNames = []string{defaultAuthHeader}
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for health check endpoints (needed for K8s probes)
-		if r.URL.Path == "/health" || r.URL.Path == "/ready" {
+		if public(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		// check banner api key
-		key := r.Header.Get("X-API-KEY")
+		var key string
+		for _, name := range headerNames {
+			if key = r.Header.Get(name); key != "" {
+				break
+			}
+		}
 		if key == "" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// statusDumpSignals trigger a repository status dump to the log.
+var statusDumpSignals = []os.Signal{syscall.SIGUSR1}
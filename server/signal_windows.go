@@ -0,0 +1,8 @@
+//go:build windows
+
+package server
+
+import "os"
+
+// statusDumpSignals is empty on Windows, which has no SIGUSR1.
+var statusDumpSignals []os.Signal
@@ -0,0 +1,39 @@
+package server
+
+import "runtime/debug"
+
+// BuildInfo describes the running build of the server, as served by /version.
+type BuildInfo struct {
+	Version     string `json:"version,omitempty"`      // Server.Version, set by the application
+	GoVersion   string `json:"go_version"`             // Go toolchain the binary was built with
+	Path        string `json:"path,omitempty"`         // Main module path
+	ModVersion  string `json:"mod_version,omitempty"`  // Main module version, "(devel)" for local builds
+	VCSRevision string `json:"vcs_revision,omitempty"` // Commit the binary was built from
+	VCSTime     string `json:"vcs_time,omitempty"`     // Commit time, RFC 3339
+	VCSModified bool   `json:"vcs_modified"`           // Whether the working tree had uncommitted changes
+}
+
+// BuildInfo returns the server's Version together with the build information
+// embedded in the binary by the Go toolchain. The VCS fields are only set
+// for binaries built from a checkout with module mode (go build, not go run).
+func (s *Server) BuildInfo() BuildInfo {
+	info := BuildInfo{Version: s.Version}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	info.Path = build.Main.Path
+	info.ModVersion = build.Main.Version
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.VCSRevision = setting.Value
+		case "vcs.time":
+			info.VCSTime = setting.Value
+		case "vcs.modified":
+			info.VCSModified = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,117 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// archiveExtensions are the files read from an archive; everything else in it
+// is ignored.
+var archiveExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// expandArchives replaces each object with the configuration files of the
+// .tar.gz, .tar or .zip archive it contains. The files of one archive are
+// returned in lexical order of their paths, so decodeObjects merges them
+// deterministically: a later path overrides the top-level keys of an earlier
+// one, and the files of a later archive override those of an earlier one.
+func expandArchives(name string, objects []object) ([]object, error) {
+	var out []object
+	for _, obj := range objects {
+		files, err := readArchive(obj)
+		if err != nil {
+			return nil, withKind(ErrParse, fmt.Errorf("repository %q: error reading archive %s: %w", name, obj.location, err))
+		}
+		if len(files) == 0 {
+			return nil, withKind(ErrParse, fmt.Errorf("repository %q: archive %s contains no YAML or JSON files", name, obj.location))
+		}
+		out = append(out, files...)
+	}
+	return out, nil
+}
+
+// readArchive returns the configuration files in an archive, sorted by path.
+// The format is detected from the content rather than the object name.
+func readArchive(obj object) ([]object, error) {
+	content, err := gunzip(obj.content)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []object
+	switch {
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		files, err = readZip(obj.location, content)
+	case len(content) > 262 && string(content[257:262]) == "ustar":
+		files, err = readTar(obj.location, content)
+	default:
+		return nil, errors.New("not a .tar.gz, .tar or .zip archive")
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].location < files[j].location })
+	return files, nil
+}
+
+// readTar returns the configuration files in a tar archive.
+func readTar(location string, content []byte) ([]object, error) {
+	var files []object
+	reader := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !isConfigFile(header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, object{location: location + "!/" + header.Name, content: data})
+	}
+}
+
+// readZip returns the configuration files in a zip archive.
+func readZip(location string, content []byte) ([]object, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	var files []object
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !isConfigFile(file.Name) {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, object{location: location + "!/" + file.Name, content: data})
+	}
+	return files, nil
+}
+
+// isConfigFile reports whether an archive entry is a YAML or JSON file.
+// Hidden files, such as the ._ metadata files macOS adds to archives, are
+// skipped.
+func isConfigFile(name string) bool {
+	base := path.Base(name)
+	return !strings.HasPrefix(base, ".") && archiveExtensions[strings.ToLower(path.Ext(base))]
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+// tarGzBytes returns a .tar.gz archive holding files, written in the given order.
+func tarGzBytes(t *testing.T, files [][2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file[0], Mode: 0o644, Size: int64(len(file[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(file[1])); err != nil {
+			t.Fatalf("Failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// zipBytes returns a .zip archive holding files, written in the given order.
+func zipBytes(t *testing.T, files [][2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestExpandArchivesZip tests that zip entries are filtered and sorted by path
+func TestExpandArchivesZip(t *testing.T) {
+	content := zipBytes(t, [][2]string{
+		{"b/routes.json", `{"timeout": 30}`},
+		{"a/flags.yaml", "timeout: 10\n"},
+		{"README.md", "# docs\n"},
+		{"a/._flags.yaml", "junk"},
+	})
+	files, err := expandArchives("bundle", []object{{location: "gs://config/bundle.zip", content: content}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := []string{"gs://config/bundle.zip!/a/flags.yaml", "gs://config/bundle.zip!/b/routes.json"}
+	if len(files) != len(want) {
+		t.Fatalf("Expected %d files, got %d", len(want), len(files))
+	}
+	for i, file := range files {
+		if file.location != want[i] {
+			t.Errorf("Expected file %d to be %s, got %s", i, want[i], file.location)
+		}
+	}
+
+	data, _, err := DecodeOptions{}.decodeObjects("bundle", files)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data["timeout"] != 30 {
+		t.Errorf("Expected the later path to win, got %v", data["timeout"])
+	}
+}
+
+// TestExpandArchivesErrors tests that unreadable and empty archives are parse errors
+func TestExpandArchivesErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"not an archive": []byte("key: value\n"),
+		"no config":      tarGzBytes(t, [][2]string{{"notes.txt", "hello"}}),
+	}
+	for name, content := range tests {
+		_, err := expandArchives("bundle", []object{{location: "s3://config/bundle", content: content}})
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("%s: Expected ErrParse, got: %v", name, err)
+		}
+	}
+}
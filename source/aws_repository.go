@@ -2,24 +2,32 @@ package source
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"gopkg.in/yaml.v3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
 )
 
 // AwsS3Repository is a struct that implements the Repository interface for
 // handling configuration data stored in a YAML file within an S3 bucket.
 type AwsS3Repository struct {
 	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
 	Name          string                 // Name of the configuration source
 	data          map[string]interface{} // Map to store the configuration data
 	BucketName    string                 // Name of the S3 bucket
 	ObjectName    string                 // Name of the YAML file within the S3 bucket
+	ObjectNames   []string               // Several YAML files merged in order, used instead of ObjectName when set
+	Archive       bool                   // Objects are .tar.gz, .tar or .zip bundles whose YAML and JSON files are merged
 	Client        *s3.Client             // S3 client instance
 	rawData       []byte                 // Raw data of the YAML configuration file
 	clientOnce    sync.Once              // Ensures client is initialized only once
@@ -27,18 +35,27 @@ type AwsS3Repository struct {
 }
 
 // Refresh reads the YAML file from the S3 bucket, unmarshal it into the data map.
+// With ObjectNames, every object is read and their top-level keys are merged
+// in order, later objects taking precedence; GetRawData then returns the
+// merged document. With Archive, each object is a bundle whose configuration
+// files are merged in lexical order of their paths.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
 func (a *AwsS3Repository) Refresh() error {
+	return a.cached(a, a.DecodeOptions, "s3://"+a.BucketName+"/"+strings.Join(objectNames(a.ObjectName, a.ObjectNames), ","), a.refresh, func(data map[string]interface{}, rawData []byte) {
+		a.Lock()
+		a.data, a.rawData = data, rawData
+		a.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (a *AwsS3Repository) refresh() error {
 	ctx := context.Background()
 
 	// Thread-safe client initialization using sync.Once (only if client not pre-configured)
 	if a.Client == nil {
 		a.clientOnce.Do(func() {
-			cfg, err := config.LoadDefaultConfig(ctx)
-			if err != nil {
-				a.clientInitErr = fmt.Errorf("failed to load AWS config: %w", err)
-				return
-			}
-			a.Client = s3.NewFromConfig(cfg)
+			a.Client, a.clientInitErr = NewSharedS3Client(ctx)
 		})
 		if a.clientInitErr != nil {
 			return a.clientInitErr
@@ -46,24 +63,24 @@ func (a *AwsS3Repository) Refresh() error {
 	}
 
 	// Network I/O outside lock for better performance
-	result, err := a.Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(a.BucketName),
-		Key:    aws.String(a.ObjectName),
-	})
-	if err != nil {
-		return err
+	var objects []object
+	for _, key := range objectNames(a.ObjectName, a.ObjectNames) {
+		fileContent, err := a.getObject(ctx, key)
+		if err != nil {
+			return withKind(s3Kind(err), err)
+		}
+		objects = append(objects, object{location: "s3://" + a.BucketName + "/" + key, content: fileContent})
 	}
-	defer result.Body.Close()
 
-	// Read the file content from the reader.
-	fileContent, err := io.ReadAll(result.Body)
-	if err != nil {
-		return err
+	if a.Archive {
+		var err error
+		if objects, err = expandArchives(a.Name, objects); err != nil {
+			return err
+		}
 	}
 
 	// Unmarshal to temp variable outside lock to prevent data corruption on error
-	var tempData map[string]interface{}
-	err = yaml.Unmarshal(fileContent, &tempData)
+	tempData, fileContent, err := a.decodeObjects(a.Name, objects)
 	if err != nil {
 		return err
 	}
@@ -77,11 +94,67 @@ func (a *AwsS3Repository) Refresh() error {
 	return nil
 }
 
+// NewSharedS3Client creates an S3 client from the default AWS configuration.
+// Repositories without a Client each create their own on first refresh; set
+// Client to one shared client instead when many repositories read the same
+// account, so they share one connection pool and credential cache:
+//
+//	client, err := source.NewSharedS3Client(ctx)
+//	flags := &source.AwsS3Repository{Name: "flags", BucketName: "config", ObjectName: "flags.yaml", Client: client}
+//	routes := &source.AwsS3Repository{Name: "routes", BucketName: "config", ObjectName: "routes.yaml", Client: client}
+func NewSharedS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+// s3Kind maps an S3 error to an error kind, or nil if it has none.
+func s3Kind(err error) error {
+	var noSuchKey *types.NoSuchKey
+	var noSuchBucket *types.NoSuchBucket
+	if errors.As(err, &noSuchKey) || errors.As(err, &noSuchBucket) {
+		return ErrNotFound
+	}
+	return awsKind(err)
+}
+
+// awsKind maps an AWS SDK error to an error kind by the status code of the
+// failed response, or nil if it has none.
+func awsKind(err error) error {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		return statusKind(respErr.HTTPStatusCode())
+	}
+	return networkKind(err)
+}
+
+// getObject reads the content of one object in the bucket.
+func (a *AwsS3Repository) getObject(ctx context.Context, key string) ([]byte, error) {
+	result, err := a.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	// Read the file content from the reader.
+	return io.ReadAll(result.Body)
+}
+
 // GetName returns the name of the configuration source.
 func (a *AwsS3Repository) GetName() string {
 	return a.Name
 }
 
+// Type returns the repository type.
+func (a *AwsS3Repository) Type() string {
+	return TypeAwsS3
+}
+
 // GetData returns the configuration data as a map of configuration names to their respective models.
 func (a *AwsS3Repository) GetData(configName string) (config interface{}, isPresent bool) {
 	a.RLock()
@@ -90,6 +163,20 @@ func (a *AwsS3Repository) GetData(configName string) (config interface{}, isPres
 	return config, isPresent
 }
 
+// GetAllData returns a deep copy of the decoded configuration map.
+func (a *AwsS3Repository) GetAllData() map[string]interface{} {
+	a.RLock()
+	defer a.RUnlock()
+	return deepcopy.Map(a.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (a *AwsS3Repository) Keys() []string {
+	a.RLock()
+	defer a.RUnlock()
+	return mapKeys(a.data)
+}
+
 // GetRawData returns the raw data of the YAML configuration file.
 func (a *AwsS3Repository) GetRawData() []byte {
 	a.RLock()
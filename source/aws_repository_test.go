@@ -0,0 +1,86 @@
+package source
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// TestAwsS3RepositoryErrorKinds tests that S3 error responses are tagged with their kind
+func TestAwsS3RepositoryErrorKinds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Path {
+		case "/config/config.yaml":
+			w.Write([]byte("key: [value"))
+		case "/config/secret.yaml":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	}))
+	defer server.Close()
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+
+	tests := []struct {
+		object string
+		want   error
+	}{
+		{"missing.yaml", ErrNotFound},
+		{"secret.yaml", ErrPermission},
+		{"config.yaml", ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			repo := &AwsS3Repository{Name: "test", BucketName: "config", ObjectName: tt.object, Client: client}
+			if err := repo.Refresh(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestAwsS3RepositoryMalformedKeepsData tests that a malformed object does not replace the last good data
+func TestAwsS3RepositoryMalformedKeepsData(t *testing.T) {
+	var content atomic.Value
+	content.Store("key: value\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+
+	repo := &AwsS3Repository{Name: "test", BucketName: "config", ObjectName: "config.yaml", Client: client}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content.Store("key: [value\n")
+	if err := repo.Refresh(); !errors.Is(err, ErrParse) {
+		t.Fatalf("Expected a parse error, got: %v", err)
+	}
+	if val, _ := repo.GetData("key"); val != "value" {
+		t.Errorf("Expected the last good data to survive, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "key: value\n" {
+		t.Errorf("Expected the last good raw data to survive, got %q", raw)
+	}
+}
@@ -0,0 +1,150 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// DiskCache persists the last good data of a network repository, so a
+// process that restarts while its source is down still starts with config.
+// It is embedded in the network repositories in this package; set CacheDir
+// on the repository to enable it.
+//
+// Every successful refresh that changes the data writes it to a file in
+// CacheDir, atomically (a temporary file renamed into place). When a refresh
+// fails before the repository has loaded any data, typically at startup, the
+// cached data is loaded instead, a warning is logged and Refresh returns
+// nil; the next failing refresh reports its error as usual. The file is
+// named after the repository type, name and source location, so changing
+// where a repository reads from never loads another source's data.
+//
+// SOPS-encrypted data decrypted with DecryptSops is cached as the encrypted
+// document only and decrypted again when it is loaded, so no plaintext
+// secrets are written to disk.
+type DiskCache struct {
+	// CacheDir is the directory the cache files are kept in. It must
+	// exist. Empty disables the cache.
+	CacheDir string
+
+	mu        sync.Mutex // Protects savedHash
+	savedHash string     // Content hash of the last data written to the cache
+}
+
+// cacheEntry is the content of a cache file.
+type cacheEntry struct {
+	Raw  string                 `yaml:"raw"`
+	Data map[string]interface{} `yaml:"data,omitempty"` // Omitted for SOPS-encrypted raw data, which is decoded again on load
+}
+
+// unsafeFileChars matches the characters not kept from repository names in
+// cache file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// cachePath returns the cache file of the repository reading location.
+func (d *DiskCache) cachePath(repo Repository, location string) string {
+	id := ContentHash([]byte(repo.Type() + "\x00" + repo.GetName() + "\x00" + location))[:16]
+	name := unsafeFileChars.ReplaceAllString(repo.GetName(), "_")
+	return filepath.Join(d.CacheDir, fmt.Sprintf("%s-%s-%s.yaml", repo.Type(), name, id))
+}
+
+// cached runs refresh for repo, which reads location and decodes it with
+// options. On success the repository's data is written to the cache; on
+// failure, if repo has no data yet, the cached data is passed to set
+// instead. Without a CacheDir it just runs refresh.
+func (d *DiskCache) cached(repo Repository, options DecodeOptions, location string, refresh func() error, set func(data map[string]interface{}, rawData []byte)) error {
+	if d.CacheDir == "" {
+		return refresh()
+	}
+	path := d.cachePath(repo, location)
+	err := refresh()
+	if err == nil {
+		d.save(repo, options, path)
+		return nil
+	}
+	if repo.GetRawData() != nil {
+		return err
+	}
+	data, rawData, loadErr := d.load(repo, options, path)
+	if loadErr != nil {
+		if !os.IsNotExist(loadErr) {
+			logrus.WithError(loadErr).WithField("repository", repo.GetName()).Warn("error loading cached config")
+		}
+		return err
+	}
+	set(data, rawData)
+	logrus.WithError(err).WithField("repository", repo.GetName()).WithField("cache", path).Warn("refresh failed, serving cached config")
+	return nil
+}
+
+// save writes the repository's current data to path unless it is unchanged
+// since the last save. Data decrypted from SOPS-encrypted raw data is left
+// out. Errors are logged: failing to cache must not fail a refresh that
+// succeeded.
+func (d *DiskCache) save(repo Repository, options DecodeOptions, path string) {
+	rawData := repo.GetRawData()
+	hash := ContentHash(rawData)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if hash == d.savedHash {
+		return
+	}
+	entry := cacheEntry{Raw: string(rawData)}
+	if !options.DecryptSops || !hasSopsMetadata(rawData) {
+		entry.Data = AllData(repo)
+	}
+	content, err := yaml.Marshal(entry)
+	if err == nil {
+		err = writeFileAtomic(path, content)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("repository", repo.GetName()).Warn("error caching config")
+		return
+	}
+	d.savedHash = hash
+}
+
+// load reads the cache file at path. An entry without data holds a
+// SOPS-encrypted document, which is decoded with options again.
+func (d *DiskCache) load(repo Repository, options DecodeOptions, path string) (map[string]interface{}, []byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry cacheEntry
+	if err := yaml.Unmarshal(content, &entry); err != nil {
+		return nil, nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	if entry.Data == nil && entry.Raw != "" {
+		data, rawData, err := options.decode(repo.GetName(), path, []byte(entry.Raw))
+		return data, rawData, err
+	}
+	return entry.Data, []byte(entry.Raw), nil
+}
+
+// writeFileAtomic writes content to a temporary file next to path and
+// renames it into place, so readers never see a partial file.
+func writeFileAtomic(path string, content []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
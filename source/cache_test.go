@@ -0,0 +1,163 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDiskCacheColdStart tests that a repository starting while its backend is down serves the cached data
+func TestDiskCacheColdStart(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("timeout: 30\n"))
+	}))
+	defer server.Close()
+	configURL, _ := url.Parse(server.URL + "/config.yaml")
+	dir := t.TempDir()
+
+	// A previous run fetched the config and cached it
+	previous := &WebRepository{Name: "app", URL: configURL, DiskCache: DiskCache{CacheDir: dir}}
+	if err := previous.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read cache dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected exactly one cache file and no temporary files, got %d entries", len(entries))
+	}
+
+	// The process restarts while the backend is down
+	down.Store(true)
+	repo := &WebRepository{Name: "app", URL: configURL, DiskCache: DiskCache{CacheDir: dir}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected the cached config to be served, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected timeout 30 from the cache, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "timeout: 30\n" {
+		t.Errorf("Expected the cached raw data, got %q", raw)
+	}
+
+	// Once loaded, failures are reported again and the data is kept
+	if err := repo.Refresh(); err == nil {
+		t.Error("Expected the next failing refresh to return its error")
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected the cached data to be kept, got %v", val)
+	}
+
+	// A repository reading another source never loads this cache
+	otherURL, _ := url.Parse(server.URL + "/other.yaml")
+	other := &WebRepository{Name: "app", URL: otherURL, DiskCache: DiskCache{CacheDir: dir}}
+	if err := other.Refresh(); err == nil {
+		t.Error("Expected an error without a cache for the source")
+	}
+	if other.GetAllData() != nil {
+		t.Error("Expected no data without a cache for the source")
+	}
+
+	// Without CacheDir nothing is cached
+	uncached := &WebRepository{Name: "app", URL: configURL}
+	if err := uncached.Refresh(); err == nil {
+		t.Error("Expected an error without a cache")
+	}
+}
+
+// TestDiskCacheUpdates tests that the cache follows changes to the source
+func TestDiskCacheUpdates(t *testing.T) {
+	var content atomic.Value
+	content.Store("timeout: 30\n")
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+	configURL, _ := url.Parse(server.URL)
+	dir := t.TempDir()
+
+	repo := &WebRepository{Name: "app", URL: configURL, DiskCache: DiskCache{CacheDir: dir}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	content.Store("timeout: 60\n")
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	down.Store(true)
+	restarted := &WebRepository{Name: "app", URL: configURL, DiskCache: DiskCache{CacheDir: dir}}
+	if err := restarted.Refresh(); err != nil {
+		t.Fatalf("Expected the cached config to be served, got: %v", err)
+	}
+	if val, _ := restarted.GetData("timeout"); val != 60 {
+		t.Errorf("Expected the latest data in the cache, got %v", val)
+	}
+}
+
+// TestDiskCacheSops tests that decrypted SOPS values are never written to the cache
+func TestDiskCacheSops(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/sops/age.key")
+	encrypted, err := os.ReadFile(sopsFixture)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(encrypted)
+	}))
+	defer server.Close()
+	configURL, _ := url.Parse(server.URL + "/secrets.yaml")
+	dir := t.TempDir()
+
+	repo := &WebRepository{Name: "secrets", URL: configURL, DecodeOptions: DecodeOptions{DecryptSops: true}, DiskCache: DiskCache{CacheDir: dir}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one cache file, got %d entries: %v", len(entries), err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read cache file: %v", err)
+	}
+	for _, secret := range []string{"s3cr3t", "hunter2"} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("Expected no decrypted values in the cache, found %q", secret)
+		}
+	}
+
+	// The cached document is decrypted again when loaded
+	down.Store(true)
+	restarted := &WebRepository{Name: "secrets", URL: configURL, DecodeOptions: DecodeOptions{DecryptSops: true}, DiskCache: DiskCache{CacheDir: dir}}
+	if err := restarted.Refresh(); err != nil {
+		t.Fatalf("Expected the cached config to be served, got: %v", err)
+	}
+	if val, _ := restarted.GetData("api_token"); val != "s3cr3t" {
+		t.Errorf("Expected decrypted api_token from the cache, got %v", val)
+	}
+	if raw := string(restarted.GetRawData()); strings.Contains(raw, "hunter2") {
+		t.Errorf("Expected raw data to stay encrypted, got: %s", raw)
+	}
+}
@@ -0,0 +1,162 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ConfigMapRepository is a struct that implements the Repository interface for
+// handling configuration data stored under a key of a Kubernetes ConfigMap.
+type ConfigMapRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
+	Namespace     string                 // Namespace of the ConfigMap
+	ConfigMapName string                 // Name of the ConfigMap, defaults to Name
+	DataKey       string                 // Key within the ConfigMap data holding the YAML configuration
+	Client        kubernetes.Interface   // Optional Kubernetes client; built from Kubeconfig or the in-cluster config when nil
+	Kubeconfig    string                 // Optional kubeconfig path used when Client is nil; empty means in-cluster
+	data          map[string]interface{} // Map to store the configuration data
+	rawData       []byte                 // Raw data of the YAML configuration file
+	clientOnce    sync.Once              // Ensures client is initialized only once
+	clientInitErr error                  // Stores error from client initialization
+}
+
+// Refresh reads the data key of the ConfigMap, unmarshal it into the data map.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
+func (c *ConfigMapRepository) Refresh() error {
+	return c.cached(c, c.DecodeOptions, c.Namespace+"/"+c.ConfigMapName+"/"+c.DataKey, c.refresh, func(data map[string]interface{}, rawData []byte) {
+		c.Lock()
+		c.data, c.rawData = data, rawData
+		c.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (c *ConfigMapRepository) refresh() error {
+	ctx := context.Background()
+
+	// Thread-safe client initialization using sync.Once (only if client not pre-configured)
+	if c.Client == nil {
+		c.clientOnce.Do(func() {
+			var cfg *rest.Config
+			var err error
+			if c.Kubeconfig != "" {
+				cfg, err = clientcmd.BuildConfigFromFlags("", c.Kubeconfig)
+			} else {
+				cfg, err = rest.InClusterConfig()
+			}
+			if err != nil {
+				c.clientInitErr = fmt.Errorf("failed to load Kubernetes config: %w", err)
+				return
+			}
+			c.Client, c.clientInitErr = kubernetes.NewForConfig(cfg)
+		})
+		if c.clientInitErr != nil {
+			return c.clientInitErr
+		}
+	}
+
+	// Network I/O outside lock for better performance
+	configMap, err := c.Client.CoreV1().ConfigMaps(c.Namespace).Get(ctx, c.configMapName(), metav1.GetOptions{})
+	if err != nil {
+		return withKind(kubernetesKind(err), err)
+	}
+	location := c.location()
+	content, ok := configMap.Data[c.DataKey]
+	if !ok {
+		return withKind(ErrNotFound, fmt.Errorf("repository %q: key %q not found in %s", c.Name, c.DataKey, location))
+	}
+	fileContent := []byte(content)
+
+	// Unmarshal to temp variable outside lock to prevent data corruption on error
+	tempData, fileContent, err := c.decode(c.Name, location, fileContent)
+	if err != nil {
+		return err
+	}
+
+	// Only lock for atomic data swap
+	c.Lock()
+	c.data = tempData
+	c.rawData = fileContent
+	c.Unlock()
+
+	return nil
+}
+
+// kubernetesKind maps a Kubernetes API error to an error kind, or nil if it
+// has none.
+func kubernetesKind(err error) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return ErrNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return ErrPermission
+	case apierrors.IsServiceUnavailable(err), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return ErrUnreachable
+	default:
+		return networkKind(err)
+	}
+}
+
+// configMapName returns the name of the ConfigMap to read.
+func (c *ConfigMapRepository) configMapName() string {
+	if c.ConfigMapName != "" {
+		return c.ConfigMapName
+	}
+	return c.Name
+}
+
+// location describes the ConfigMap key for error messages.
+func (c *ConfigMapRepository) location() string {
+	return "configmap " + c.Namespace + "/" + c.configMapName() + ":" + c.DataKey
+}
+
+// GetName returns the name of the configuration source.
+func (c *ConfigMapRepository) GetName() string {
+	return c.Name
+}
+
+// Type returns the repository type.
+func (c *ConfigMapRepository) Type() string {
+	return TypeConfigMap
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (c *ConfigMapRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	c.RLock()
+	defer c.RUnlock()
+	config, isPresent = c.data[configName]
+	return config, isPresent
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (c *ConfigMapRepository) GetAllData() map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+	return deepcopy.Map(c.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (c *ConfigMapRepository) Keys() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return mapKeys(c.data)
+}
+
+// GetRawData returns the raw data of the YAML configuration.
+func (c *ConfigMapRepository) GetRawData() []byte {
+	c.RLock()
+	defer c.RUnlock()
+	return c.rawData
+}
@@ -0,0 +1,120 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newConfigMap returns a ConfigMap in the "config" namespace with the given data
+func newConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "config"},
+		Data:       data,
+	}
+}
+
+// TestConfigMapRepositoryRefresh tests reading and re-reading a ConfigMap data key
+func TestConfigMapRepositoryRefresh(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap("app-config", map[string]string{
+		"config.yaml": "name: app\nport: 8080\n",
+	}))
+	repo := &ConfigMapRepository{
+		Name:          "app",
+		Namespace:     "config",
+		ConfigMapName: "app-config",
+		DataKey:       "config.yaml",
+		Client:        client,
+	}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("port"); val != 8080 {
+		t.Errorf("Expected port 8080, got %v", val)
+	}
+	if string(repo.GetRawData()) != "name: app\nport: 8080\n" {
+		t.Errorf("Expected raw data to match, got: %s", string(repo.GetRawData()))
+	}
+
+	// Polling picks up updates to the ConfigMap
+	updated := newConfigMap("app-config", map[string]string{"config.yaml": "name: app\nport: 9090\n"})
+	if _, err := client.CoreV1().ConfigMaps("config").Update(context.Background(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update ConfigMap: %v", err)
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("port"); val != 9090 {
+		t.Errorf("Expected port 9090 after update, got %v", val)
+	}
+}
+
+// TestConfigMapRepositoryDefaultsToName tests that Name is used when ConfigMapName is empty
+func TestConfigMapRepositoryDefaultsToName(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap("app", map[string]string{"config.yaml": "key: value\n"}))
+	repo := &ConfigMapRepository{Name: "app", Namespace: "config", DataKey: "config.yaml", Client: client}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("key"); val != "value" {
+		t.Errorf("Expected 'value', got '%v'", val)
+	}
+}
+
+// TestConfigMapRepositoryErrors tests missing ConfigMaps, missing keys, and malformed YAML
+func TestConfigMapRepositoryErrors(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap("app", map[string]string{
+		"config.yaml": "key: value\n",
+		"broken.yaml": "list: [a, b\n",
+	}))
+
+	tests := []struct {
+		name    string
+		repo    *ConfigMapRepository
+		wantErr string
+		kind    error
+	}{
+		{"missing configmap", &ConfigMapRepository{Name: "missing", Namespace: "config", DataKey: "config.yaml", Client: client}, "not found", ErrNotFound},
+		{"missing key", &ConfigMapRepository{Name: "app", Namespace: "config", DataKey: "other.yaml", Client: client}, `key "other.yaml" not found`, ErrNotFound},
+		{"malformed yaml", &ConfigMapRepository{Name: "app", Namespace: "config", DataKey: "broken.yaml", Client: client}, "configmap config/app:broken.yaml", ErrParse},
+	}
+	for _, tt := range tests {
+		err := tt.repo.Refresh()
+		if err == nil {
+			t.Errorf("%s: Expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Expected error to contain %q, got: %v", tt.name, tt.wantErr, err)
+		}
+		if !errors.Is(err, tt.kind) {
+			t.Errorf("%s: Expected %v, got: %v", tt.name, tt.kind, err)
+		}
+		if tt.repo.GetRawData() != nil {
+			t.Errorf("%s: Expected no data after failed refresh", tt.name)
+		}
+	}
+}
+
+// TestConfigMapRepositoryForbidden tests that a forbidden read is reported as ErrPermission
+func TestConfigMapRepositoryForbidden(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("configmaps"), "app", errors.New("no RBAC"))
+	})
+	repo := &ConfigMapRepository{Name: "app", Namespace: "config", DataKey: "config.yaml", Client: client}
+
+	if err := repo.Refresh(); !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission, got: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// mergeKey is the YAML merge key. yaml.v3 expands it into the surrounding
+// mapping while decoding, so it should never survive into the data map.
+const mergeKey = "<<"
+
+// EmptyPolicy decides what a refresh does when the configuration decodes to
+// no keys, as an empty, whitespace-only or comment-only file does.
+type EmptyPolicy int
+
+const (
+	// EmptyWarn logs a warning and serves the empty configuration.
+	EmptyWarn EmptyPolicy = iota
+	// EmptyError fails the refresh with an ErrParse error, so the previous
+	// data keeps being served.
+	EmptyError
+	// EmptyAllow serves the empty configuration silently.
+	EmptyAllow
+)
+
+// DecodeOptions controls how a repository turns raw configuration bytes into
+// its data map. It is embedded in every repository in this package, so its
+// fields can be set directly on the repository.
+type DecodeOptions struct {
+	// VerifyMergeKeys fails the refresh if a "<<" key is left in the decoded
+	// data, which happens when a merge key is quoted (e.g. after a JSON
+	// round trip) and was therefore treated as a literal key instead of
+	// being expanded.
+	VerifyMergeKeys bool
+
+	// DecryptSops decrypts documents encrypted with SOPS before decoding
+	// them, using the keys SOPS finds in the environment (e.g. age keys from
+	// SOPS_AGE_KEY_FILE, or cloud KMS credentials). Documents without SOPS
+	// metadata are decoded as usual. GetRawData keeps returning the
+	// encrypted bytes, so plaintext is never served from the raw data.
+	DecryptSops bool
+
+	// Template renders the document as a Go text/template before decoding
+	// it, so values can be computed, e.g.
+	// "url: http://{{ .Env.DB_HOST }}:{{ env "DB_PORT" | default "5432" }}".
+	// Templates see the environment as .Env and can use the env, default,
+	// required, lower, upper and trim functions. A template error fails the
+	// refresh and keeps the previous data. GetRawData returns the rendered
+	// document, unless it was decrypted with DecryptSops.
+	Template bool
+
+	// PreserveBigNumbers decodes numbers that a Go int64, uint64 or float64
+	// cannot hold exactly (integers beyond the 64-bit range and decimals
+	// with more than 15 significant digits) as their literal strings instead
+	// of rounding them to float64, so big IDs and exact decimals survive.
+	// Read them with the client's GetConfigBigInt and GetConfigBigFloat.
+	PreserveBigNumbers bool
+
+	// RootKey mounts a sub-map of the document as the repository's data, so
+	// an object holding several teams' config under top-level keys can be
+	// read one team at a time. Nested keys are separated by dots, e.g.
+	// "teams.payments". The refresh fails if the key is absent or not a map.
+	// GetRawData returns the selected sub-document, unless it was decrypted
+	// with DecryptSops.
+	RootKey string
+
+	// OnEmpty decides what happens when the configuration has no keys. An
+	// empty file usually means a truncated upload or a bad deploy, so the
+	// default logs a warning; set EmptyError to keep the previous data
+	// instead, or EmptyAllow when an empty configuration is expected.
+	OnEmpty EmptyPolicy
+
+	// Validator, when set, is called with the decoded data before it is
+	// swapped in. Returning an error fails the refresh and keeps the
+	// previous data, so a config that parses but is semantically wrong
+	// (e.g. a negative timeout) is never served.
+	Validator func(map[string]interface{}) error
+
+	// Transforms are applied in order to the document before it is decoded,
+	// after DecryptSops and Template, e.g. to interpolate values or convert
+	// another format to YAML. Each receives the previous one's output. An
+	// error fails the refresh with ErrParse and keeps the previous data.
+	// GetRawData returns the transformed document, unless it was decrypted
+	// with DecryptSops.
+	Transforms []func([]byte) ([]byte, error)
+
+	// MapTransforms are applied in order to the decoded data, after RootKey
+	// and before the Validator, and may modify it in place, e.g. to fill in
+	// derived keys. An error fails the refresh and keeps the previous data.
+	// They change what GetData and GetAllData return; GetRawData is only
+	// affected where it is re-encoded from the data (with RootKey or
+	// several merged objects).
+	MapTransforms []func(map[string]interface{}) error
+}
+
+// decode unmarshals raw configuration data into a map and returns it along
+// with the raw data the repository should expose (see prepare). A stream of
+// "---"-separated documents is merged into one map (see decodeNodes). Errors
+// are wrapped with the repository name and source location so a malformed
+// file can be traced back to where it came from; the yaml error itself
+// carries the line.
+func (o DecodeOptions) decode(name, location string, data []byte) (map[string]interface{}, []byte, error) {
+	plaintext, encrypted, err := o.prepare(name, location, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	docs, err := parseDocuments(plaintext)
+	if err != nil {
+		return nil, nil, withKind(ErrParse, fmt.Errorf("repository %q: error decoding %s: %w", name, location, err))
+	}
+	out, err := o.decodeNodes(name, location, docs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if encrypted {
+		return out, data, nil
+	}
+	if o.RootKey != "" {
+		plaintext, err = yaml.Marshal(out)
+		if err != nil {
+			return nil, nil, fmt.Errorf("repository %q: error encoding %s of %s: %w", name, o.RootKey, location, err)
+		}
+	}
+	return out, plaintext, nil
+}
+
+// parseDocuments parses every document in a YAML stream. Empty input has no
+// documents.
+func parseDocuments(data []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+// prepare decrypts, renders and transforms raw configuration data according to o,
+// returning the document to decode and whether it was decrypted. Decrypted
+// documents must not be exposed as raw data; otherwise the returned document
+// is what the repository exposes, so consumers of the raw data see rendered
+// values.
+func (o DecodeOptions) prepare(name, location string, data []byte) ([]byte, bool, error) {
+	plaintext, encrypted, err := o.decryptSops(name, location, data)
+	if err != nil {
+		return nil, false, err
+	}
+	plaintext, err = o.render(name, location, plaintext)
+	if err != nil {
+		return nil, false, err
+	}
+	for i, transform := range o.Transforms {
+		if plaintext, err = transform(plaintext); err != nil {
+			return nil, false, withKind(ErrParse, fmt.Errorf("repository %q: transform %d of %s failed: %w", name, i, location, err))
+		}
+	}
+	return plaintext, encrypted, nil
+}
+
+// decodeNodes is like decode for documents that have already been parsed.
+// Successive documents are merged into one map, later documents overriding
+// the top-level keys of earlier ones. No documents decode to a nil map.
+func (o DecodeOptions) decodeNodes(name, location string, docs []*yaml.Node) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	for _, doc := range docs {
+		if o.PreserveBigNumbers {
+			preserveBigNumbers(doc)
+		}
+		var next map[string]interface{}
+		if err := doc.Decode(&next); err != nil {
+			return nil, withKind(ErrParse, fmt.Errorf("repository %q: error decoding %s: %w", name, location, err))
+		}
+		if out == nil {
+			out = next
+			continue
+		}
+		for key, value := range next {
+			out[key] = value
+		}
+	}
+	out, err := o.selectRoot(name, location, out)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.transform(name, location, out); err != nil {
+		return nil, err
+	}
+	if err := o.verify(name, location, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// selectRoot returns the sub-map of out named by RootKey, or out itself when
+// RootKey is not set.
+func (o DecodeOptions) selectRoot(name, location string, out map[string]interface{}) (map[string]interface{}, error) {
+	if o.RootKey == "" {
+		return out, nil
+	}
+	root := out
+	for _, key := range strings.Split(o.RootKey, ".") {
+		value, ok := root[key]
+		if !ok {
+			return nil, withKind(ErrNotFound, fmt.Errorf("repository %q: root key %q not found in %s", name, o.RootKey, location))
+		}
+		if root, ok = value.(map[string]interface{}); !ok {
+			return nil, withKind(ErrParse, fmt.Errorf("repository %q: root key %q in %s is not a map", name, o.RootKey, location))
+		}
+	}
+	return root, nil
+}
+
+// transform applies the MapTransforms to decoded data in order.
+func (o DecodeOptions) transform(name, location string, out map[string]interface{}) error {
+	for i, transform := range o.MapTransforms {
+		if err := transform(out); err != nil {
+			return fmt.Errorf("repository %q: map transform %d of %s failed: %w", name, i, location, err)
+		}
+	}
+	return nil
+}
+
+// verify applies the checks enabled in o to decoded data, then the Validator.
+func (o DecodeOptions) verify(name, location string, out map[string]interface{}) error {
+	if err := o.checkEmpty(name, location, out); err != nil {
+		return err
+	}
+	if o.VerifyMergeKeys {
+		if path, ok := findMergeKey(out, nil); ok {
+			return withKind(ErrParse, fmt.Errorf("repository %q: error decoding %s: unexpanded merge key at %q", name, location, path))
+		}
+	}
+	if o.Validator != nil {
+		if err := o.Validator(out); err != nil {
+			return fmt.Errorf("repository %q: invalid config in %s: %w", name, location, err)
+		}
+	}
+	return nil
+}
+
+// checkEmpty applies OnEmpty to decoded data without keys.
+func (o DecodeOptions) checkEmpty(name, location string, out map[string]interface{}) error {
+	if len(out) > 0 {
+		return nil
+	}
+	switch o.OnEmpty {
+	case EmptyError:
+		return withKind(ErrParse, fmt.Errorf("repository %q: %s is empty", name, location))
+	case EmptyWarn:
+		logrus.WithField("repository", name).WithField("location", location).Warn("configuration is empty")
+	}
+	return nil
+}
+
+// findMergeKey returns the dotted path of the first "<<" key left in value.
+func findMergeKey(value interface{}, path []string) (string, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := append(path[:len(path):len(path)], key)
+			if key == mergeKey {
+				return strings.Join(childPath, "."), true
+			}
+			if found, ok := findMergeKey(child, childPath); ok {
+				return found, true
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if found, ok := findMergeKey(child, append(path[:len(path):len(path)], fmt.Sprint(i))); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,182 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"gopkg.in/yaml.v3"
+)
+
+// DynamoDBRepository is a struct that implements the Repository interface for
+// handling configuration data stored in an item of a DynamoDB table.
+//
+// With AttributeName set, the named string or binary attribute holds a YAML
+// (or JSON) document that is decoded like a file. Without it, the item's
+// attributes other than the key are the configuration, one top-level key per
+// attribute; GetRawData then returns them as a YAML document.
+type DynamoDBRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
+	TableName     string                 // Name of the DynamoDB table
+	KeyName       string                 // Name of the table's string partition key
+	KeyValue      string                 // Partition key value of the configuration item
+	AttributeName string                 // Attribute holding a YAML document; empty exposes the item's attributes
+	Client        *dynamodb.Client       // DynamoDB client instance
+	data          map[string]interface{} // Map to store the configuration data
+	rawData       []byte                 // Raw data of the YAML configuration
+	clientOnce    sync.Once              // Ensures client is initialized only once
+	clientInitErr error                  // Stores error from client initialization
+}
+
+// Refresh reads the configuration item with a strongly consistent GetItem,
+// unmarshal it into the data map.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
+func (d *DynamoDBRepository) Refresh() error {
+	return d.cached(d, d.DecodeOptions, d.TableName+"/"+d.KeyName+"="+d.KeyValue+"/"+d.AttributeName, d.refresh, func(data map[string]interface{}, rawData []byte) {
+		d.Lock()
+		d.data, d.rawData = data, rawData
+		d.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (d *DynamoDBRepository) refresh() error {
+	ctx := context.Background()
+
+	// Thread-safe client initialization using sync.Once (only if client not pre-configured)
+	if d.Client == nil {
+		d.clientOnce.Do(func() {
+			cfg, err := config.LoadDefaultConfig(ctx)
+			if err != nil {
+				d.clientInitErr = fmt.Errorf("failed to load AWS config: %w", err)
+				return
+			}
+			d.Client = dynamodb.NewFromConfig(cfg)
+		})
+		if d.clientInitErr != nil {
+			return d.clientInitErr
+		}
+	}
+
+	// Network I/O outside lock for better performance
+	result, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.TableName),
+		Key: map[string]types.AttributeValue{
+			d.KeyName: &types.AttributeValueMemberS{Value: d.KeyValue},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return withKind(ErrNotFound, err)
+		}
+		return withKind(awsKind(err), err)
+	}
+	location := d.location()
+	if result.Item == nil {
+		return withKind(ErrNotFound, fmt.Errorf("repository %q: item not found in %s", d.Name, location))
+	}
+
+	// Unmarshal to temp variable outside lock to prevent data corruption on error
+	tempData, fileContent, err := d.decodeItem(location, result.Item)
+	if err != nil {
+		return err
+	}
+
+	// Only lock for atomic data swap
+	d.Lock()
+	d.data = tempData
+	d.rawData = fileContent
+	d.Unlock()
+
+	return nil
+}
+
+// decodeItem decodes the configuration held by item.
+func (d *DynamoDBRepository) decodeItem(location string, item map[string]types.AttributeValue) (map[string]interface{}, []byte, error) {
+	if d.AttributeName != "" {
+		switch value := item[d.AttributeName].(type) {
+		case *types.AttributeValueMemberS:
+			return d.decode(d.Name, location, []byte(value.Value))
+		case *types.AttributeValueMemberB:
+			return d.decode(d.Name, location, value.Value)
+		case nil:
+			return nil, nil, withKind(ErrNotFound, fmt.Errorf("repository %q: attribute %q not found in %s", d.Name, d.AttributeName, location))
+		default:
+			return nil, nil, withKind(ErrParse, fmt.Errorf("repository %q: attribute %q in %s is not a string or binary", d.Name, d.AttributeName, location))
+		}
+	}
+
+	var attributes map[string]interface{}
+	if err := attributevalue.UnmarshalMap(item, &attributes); err != nil {
+		return nil, nil, withKind(ErrParse, fmt.Errorf("repository %q: error decoding %s: %w", d.Name, location, err))
+	}
+	delete(attributes, d.KeyName)
+	// Round-trip through YAML so numbers decode as ints like in every other
+	// source. The document is generated, so it is neither a template nor
+	// SOPS-encrypted.
+	data, err := yaml.Marshal(attributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository %q: error encoding %s: %w", d.Name, location, err)
+	}
+	options := d.DecodeOptions
+	options.Template = false
+	options.DecryptSops = false
+	return options.decode(d.Name, location, data)
+}
+
+// location describes the configuration item for error messages.
+func (d *DynamoDBRepository) location() string {
+	return "dynamodb " + d.TableName + "/" + d.KeyName + "=" + d.KeyValue
+}
+
+// GetName returns the name of the configuration source.
+func (d *DynamoDBRepository) GetName() string {
+	return d.Name
+}
+
+// Type returns the repository type.
+func (d *DynamoDBRepository) Type() string {
+	return TypeDynamoDB
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (d *DynamoDBRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	d.RLock()
+	defer d.RUnlock()
+	config, isPresent = d.data[configName]
+	return config, isPresent
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (d *DynamoDBRepository) GetAllData() map[string]interface{} {
+	d.RLock()
+	defer d.RUnlock()
+	return deepcopy.Map(d.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (d *DynamoDBRepository) Keys() []string {
+	d.RLock()
+	defer d.RUnlock()
+	return mapKeys(d.data)
+}
+
+// GetRawData returns the raw data of the YAML configuration.
+func (d *DynamoDBRepository) GetRawData() []byte {
+	d.RLock()
+	defer d.RUnlock()
+	return d.rawData
+}
@@ -0,0 +1,123 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// newDynamoDBTable starts a fake DynamoDB endpoint serving GetItem for items
+// keyed by their "id" attribute, given in DynamoDB's JSON wire format, and
+// returns a client for it.
+func newDynamoDBTable(t *testing.T, table string, items map[string]string) *dynamodb.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "DynamoDB_20120810.GetItem" {
+			http.Error(w, "unsupported operation", http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			TableName      string
+			Key            map[string]map[string]string
+			ConsistentRead bool
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if input.TableName != table || !input.ConsistentRead {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		item, ok := items[input.Key["id"]["S"]]
+		if !ok {
+			w.Write([]byte("{}"))
+			return
+		}
+		w.Write([]byte(`{"Item":` + item + `}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+}
+
+// TestDynamoDBRepositoryAttribute tests reading a YAML document from an item attribute
+func TestDynamoDBRepositoryAttribute(t *testing.T) {
+	client := newDynamoDBTable(t, "config", map[string]string{
+		"app": `{"id":{"S":"app"},"config":{"S":"name: app\nport: 8080\n"}}`,
+	})
+	repo := &DynamoDBRepository{
+		Name:          "app",
+		TableName:     "config",
+		KeyName:       "id",
+		KeyValue:      "app",
+		AttributeName: "config",
+		Client:        client,
+	}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("port"); val != 8080 {
+		t.Errorf("Expected port 8080, got %v", val)
+	}
+	if string(repo.GetRawData()) != "name: app\nport: 8080\n" {
+		t.Errorf("Expected raw data to match, got: %s", string(repo.GetRawData()))
+	}
+
+	repo.AttributeName = "missing"
+	if err := repo.Refresh(); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("Expected missing attribute error, got: %v", err)
+	}
+}
+
+// TestDynamoDBRepositoryAttributes tests exposing the item's attributes as the configuration
+func TestDynamoDBRepositoryAttributes(t *testing.T) {
+	client := newDynamoDBTable(t, "config", map[string]string{
+		"app": `{"id":{"S":"app"},"timeout":{"N":"30"},"flags":{"M":{"dark_mode":{"BOOL":true}}}}`,
+	})
+	repo := &DynamoDBRepository{Name: "app", TableName: "config", KeyName: "id", KeyValue: "app", Client: client}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected timeout 30, got %v (%T)", val, val)
+	}
+	flags, _ := repo.GetData("flags")
+	if flags, ok := flags.(map[string]interface{}); !ok || flags["dark_mode"] != true {
+		t.Errorf("Expected flags.dark_mode = true, got %v", flags)
+	}
+	if _, ok := repo.GetData("id"); ok {
+		t.Error("Expected the key attribute to be left out")
+	}
+	if raw := string(repo.GetRawData()); raw != "flags:\n    dark_mode: true\ntimeout: 30\n" {
+		t.Errorf("Expected raw data to be the attributes as YAML, got %q", raw)
+	}
+}
+
+// TestDynamoDBRepositoryItemNotFound tests that a missing item fails the refresh
+func TestDynamoDBRepositoryItemNotFound(t *testing.T) {
+	client := newDynamoDBTable(t, "config", nil)
+	repo := &DynamoDBRepository{Name: "app", TableName: "config", KeyName: "id", KeyValue: "app", Client: client}
+
+	err := repo.Refresh()
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound for a missing item, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "dynamodb config/id=app") {
+		t.Errorf("Expected error to name the item, got: %v", err)
+	}
+}
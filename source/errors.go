@@ -0,0 +1,87 @@
+package source
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+)
+
+// Error kinds reported by Refresh. Repositories tag the errors they return
+// with one of these where the cause is known, so callers can handle failures
+// uniformly across sources with errors.Is; the original error stays in the
+// chain and its message is unchanged.
+var (
+	// ErrNotFound means the configuration does not exist in the source,
+	// e.g. a missing file, S3 key, GCS object, HTTP 404, ConfigMap key or
+	// DynamoDB item.
+	ErrNotFound = errors.New("config not found in source")
+	// ErrPermission means the source refused access, e.g. HTTP 401 or 403.
+	ErrPermission = errors.New("permission denied by source")
+	// ErrParse means the configuration was fetched but could not be
+	// decoded, e.g. malformed YAML or a broken template.
+	ErrParse = errors.New("config could not be parsed")
+	// ErrUnreachable means the source could not be contacted, e.g. a
+	// network error or an HTTP 502, 503 or 504.
+	ErrUnreachable = errors.New("source unreachable")
+)
+
+// kindError tags an error with one of the error kinds without changing its
+// message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// withKind tags err with kind. A nil kind returns err unchanged.
+func withKind(kind, err error) error {
+	if kind == nil || err == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// statusKind maps an HTTP status code to an error kind, or nil if it has
+// none.
+func statusKind(code int) error {
+	switch code {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrPermission
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnreachable
+	default:
+		return nil
+	}
+}
+
+// fileKind maps a file system error to an error kind, or nil if it has none.
+func fileKind(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrPermission
+	default:
+		return nil
+	}
+}
+
+// networkKind returns ErrUnreachable if err was caused by a network failure,
+// or nil otherwise.
+func networkKind(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnreachable
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package source
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FailoverRepository serves a primary repository and keeps a secondary one
+// warm as a standby. Both are refreshed on every Refresh, so when the primary
+// fails the secondary's data is already current and is served immediately.
+// Once the primary refreshes successfully again it is served again.
+type FailoverRepository struct {
+	Name      string       // Name of the configuration source
+	Labels                 // Optional labels reported in a config server's status, e.g. team or environment
+	Primary   Repository   // Repository served while it refreshes successfully
+	Secondary Repository   // Warm standby served while the primary is failing
+	mu        sync.RWMutex // Protects active
+	active    Repository   // Repository currently served, nil until one has loaded
+}
+
+// Refresh refreshes both repositories concurrently and selects the one to
+// serve. It returns nil when the primary succeeds or the secondary takes over,
+// and an error only when neither refreshed, in which case the previously
+// served data is kept.
+func (f *FailoverRepository) Refresh() error {
+	var primaryErr, secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		primaryErr = f.Primary.Refresh()
+	}()
+	go func() {
+		defer wg.Done()
+		secondaryErr = f.Secondary.Refresh()
+	}()
+	wg.Wait()
+
+	switch {
+	case primaryErr == nil:
+		if secondaryErr != nil {
+			logrus.WithError(secondaryErr).WithField("repository", f.Name).Warn("error refreshing standby repository")
+		}
+		f.setActive(f.Primary)
+		return nil
+	case secondaryErr == nil:
+		logrus.WithError(primaryErr).WithField("repository", f.Name).Warn("primary repository failed, serving secondary")
+		f.setActive(f.Secondary)
+		return nil
+	default:
+		return errors.Join(primaryErr, secondaryErr)
+	}
+}
+
+// setActive switches the repository being served.
+func (f *FailoverRepository) setActive(repo Repository) {
+	f.mu.Lock()
+	f.active = repo
+	f.mu.Unlock()
+}
+
+// current returns the repository being served, or nil.
+func (f *FailoverRepository) current() Repository {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.active
+}
+
+// FailedOver reports whether the secondary repository is being served.
+func (f *FailoverRepository) FailedOver() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.active != nil && f.active == f.Secondary
+}
+
+// GetName returns the name of the configuration source.
+func (f *FailoverRepository) GetName() string {
+	return f.Name
+}
+
+// Type returns the repository type.
+func (f *FailoverRepository) Type() string {
+	return TypeFailover
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (f *FailoverRepository) GetData(configName string) (interface{}, bool) {
+	if repo := f.current(); repo != nil {
+		return repo.GetData(configName)
+	}
+	return nil, false
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (f *FailoverRepository) GetAllData() map[string]interface{} {
+	if repo := f.current(); repo != nil {
+		return AllData(repo)
+	}
+	return nil
+}
+
+// Keys returns the top-level configuration names of the repository being
+// served.
+func (f *FailoverRepository) Keys() []string {
+	if repo := f.current(); repo != nil {
+		return Keys(repo)
+	}
+	return nil
+}
+
+// GetRawData returns the raw data of the repository being served.
+func (f *FailoverRepository) GetRawData() []byte {
+	if repo := f.current(); repo != nil {
+		return repo.GetRawData()
+	}
+	return nil
+}
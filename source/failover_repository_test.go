@@ -0,0 +1,96 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFailoverRepositoryServesWarmSecondary tests that the secondary is kept current and served on primary failure
+func TestFailoverRepositoryServesWarmSecondary(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"primary.yaml":   "source: primary\nversion: 1\n",
+		"secondary.yaml": "source: secondary\nversion: 1\n",
+	})
+	primaryPath := filepath.Join(dir, "primary.yaml")
+	secondaryPath := filepath.Join(dir, "secondary.yaml")
+	repo := &FailoverRepository{
+		Name:      "failover",
+		Primary:   &FileRepository{Name: "primary", Path: primaryPath},
+		Secondary: &FileRepository{Name: "secondary", Path: secondaryPath},
+	}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("source"); val != "primary" {
+		t.Errorf("Expected primary to be served, got %v", val)
+	}
+
+	// The standby is refreshed while the primary is healthy
+	if err := os.WriteFile(secondaryPath, []byte("source: secondary\nversion: 2\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write secondary: %v", err)
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if repo.FailedOver() {
+		t.Error("Expected primary to still be served")
+	}
+
+	// Primary fails: the already-current secondary is served on the same refresh
+	if err := os.Remove(primaryPath); err != nil {
+		t.Fatalf("Failed to remove primary: %v", err)
+	}
+	if err := os.WriteFile(secondaryPath, []byte("source: secondary\nversion: 3\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write secondary: %v", err)
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected failover without error, got: %v", err)
+	}
+	if !repo.FailedOver() {
+		t.Error("Expected secondary to be served")
+	}
+	if val, _ := repo.GetData("version"); val != 3 {
+		t.Errorf("Expected current secondary version 3, got %v", val)
+	}
+	if string(repo.GetRawData()) != "source: secondary\nversion: 3\n" {
+		t.Errorf("Expected secondary raw data, got %s", string(repo.GetRawData()))
+	}
+
+	// Primary recovers
+	if err := os.WriteFile(primaryPath, []byte("source: primary\nversion: 4\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write primary: %v", err)
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("source"); val != "primary" || repo.FailedOver() {
+		t.Errorf("Expected primary to be served again, got %v", val)
+	}
+}
+
+// TestFailoverRepositoryBothFail tests that an error is returned and the last data kept when both sources fail
+func TestFailoverRepositoryBothFail(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"primary.yaml":   "source: primary\n",
+		"secondary.yaml": "source: secondary\n",
+	})
+	repo := &FailoverRepository{
+		Name:      "failover",
+		Primary:   &FileRepository{Name: "primary", Path: filepath.Join(dir, "primary.yaml")},
+		Secondary: &FileRepository{Name: "secondary", Path: filepath.Join(dir, "secondary.yaml")},
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "primary.yaml"))
+	os.Remove(filepath.Join(dir, "secondary.yaml"))
+	if err := repo.Refresh(); err == nil {
+		t.Fatal("Expected error when both repositories fail")
+	}
+	if val, _ := repo.GetData("source"); val != "primary" {
+		t.Errorf("Expected last served data to be kept, got %v", val)
+	}
+}
@@ -1,6 +1,8 @@
 package source
 
 import (
+	"fmt"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 	"os"
@@ -10,11 +12,13 @@ import (
 // FileRepository is a struct that implements the Repository interface for
 // handling configuration data stored in a YAML file.
 type FileRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name         string                 // Name of the configuration source
-	Path         string                 // File path of the YAML configuration file
-	data         map[string]interface{} // Map to store the configuration data
-	rawData      []byte                 // Raw data of the YAML configuration file
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
+	Path          string                 // File path of the YAML configuration file
+	data          map[string]interface{} // Map to store the configuration data
+	rawData       []byte                 // Raw data of the YAML configuration file
 }
 
 // GetName returns the name of the configuration source.
@@ -22,6 +26,11 @@ func (f *FileRepository) GetName() string {
 	return f.Name
 }
 
+// Type returns the repository type.
+func (f *FileRepository) Type() string {
+	return TypeFile
+}
+
 // GetData returns the configuration data as a map of configuration names to their respective models.
 func (f *FileRepository) GetData(configName string) (config interface{}, isPresent bool) {
 	f.RLock()
@@ -30,6 +39,20 @@ func (f *FileRepository) GetData(configName string) (config interface{}, isPrese
 	return config, isPresent
 }
 
+// GetAllData returns a deep copy of the decoded configuration map.
+func (f *FileRepository) GetAllData() map[string]interface{} {
+	f.RLock()
+	defer f.RUnlock()
+	return deepcopy.Map(f.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (f *FileRepository) Keys() []string {
+	f.RLock()
+	defer f.RUnlock()
+	return mapKeys(f.data)
+}
+
 // GetRawData returns the raw data of the YAML configuration file.
 func (f *FileRepository) GetRawData() []byte {
 	f.RLock()
@@ -37,23 +60,55 @@ func (f *FileRepository) GetRawData() []byte {
 	return f.rawData
 }
 
-// Refresh reads the YAML file, unmarshal it into the data map.
+// Refresh reads the YAML file, unmarshal it into the data map. Values tagged
+// !include are replaced by the content of the named file, resolved relative
+// to the including file; when includes are present, GetRawData returns the
+// expanded document.
 func (f *FileRepository) Refresh() error {
 	// Read the YAML file (no lock needed for read)
 	data, err := os.ReadFile(f.Path)
 	if err != nil {
 		logrus.Debug("error reading file")
+		return withKind(fileKind(err), err)
+	}
+
+	plaintext, encrypted, err := f.prepare(f.Name, f.Path, data)
+	if err != nil {
+		logrus.Debug("error preparing file")
 		return err
 	}
+	if !encrypted {
+		data = plaintext
+	}
+
+	// Resolve !include directives relative to the file
+	docs, included, err := resolveIncludes(f.Path, plaintext)
+	if err != nil {
+		logrus.Debug("error resolving includes")
+		return fmt.Errorf("repository %q: %w", f.Name, err)
+	}
 
 	// Unmarshal to temp variable outside lock to prevent data corruption on error
-	var tempData map[string]interface{}
-	err = yaml.Unmarshal(data, &tempData)
+	tempData, err := f.decodeNodes(f.Name, f.Path, docs)
 	if err != nil {
 		logrus.Debug("error unmarshalling file")
 		return err
 	}
 
+	// Consumers of the raw data (e.g. clients of a config server) cannot
+	// resolve includes, so expose the expanded document instead, or just the
+	// selected root, unless that would expose decrypted secrets
+	switch {
+	case encrypted:
+	case f.RootKey != "":
+		data, err = yaml.Marshal(tempData)
+	case included:
+		data, err = encodeDocuments(docs)
+	}
+	if err != nil {
+		return fmt.Errorf("repository %q: error encoding %s: %w", f.Name, f.Path, err)
+	}
+
 	// Only lock for atomic data swap
 	f.Lock()
 	f.data = tempData
@@ -0,0 +1,465 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content to a file in a temporary directory and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// TestFileRepositoryRefresh tests basic refresh functionality
+func TestFileRepositoryRefresh(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "key: value\n")
+	repo := &FileRepository{Name: "test", Path: path}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	val, ok := repo.GetData("key")
+	if !ok || val != "value" {
+		t.Errorf("Expected 'value', got '%v'", val)
+	}
+	if string(repo.GetRawData()) != "key: value\n" {
+		t.Errorf("Expected raw data to match, got: %s", string(repo.GetRawData()))
+	}
+}
+
+// TestFileRepositoryMalformedYAML tests that decode errors name the repository, path, and line
+func TestFileRepositoryMalformedYAML(t *testing.T) {
+	path := writeConfig(t, "broken.yaml", "key: value\nlist: [a, b\n")
+	repo := &FileRepository{Name: "broken-repo", Path: path}
+
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error for malformed YAML")
+	}
+	for _, want := range []string{"broken-repo", path, "line"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestFileRepositoryMergeKeys tests that anchors and merge keys are expanded in the data map
+// while the raw data keeps the original anchored text
+func TestFileRepositoryMergeKeys(t *testing.T) {
+	content := `defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  retries: 5
+`
+	path := writeConfig(t, "merge.yaml", content)
+	repo := &FileRepository{Name: "merge", Path: path, DecodeOptions: DecodeOptions{VerifyMergeKeys: true}}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	val, ok := repo.GetData("service")
+	if !ok {
+		t.Fatal("Expected service to be present")
+	}
+	service := val.(map[string]interface{})
+	if service["timeout"] != 30 {
+		t.Errorf("Expected merged timeout 30, got %v", service["timeout"])
+	}
+	if service["retries"] != 5 {
+		t.Errorf("Expected local retries 5 to override merged value, got %v", service["retries"])
+	}
+	if _, ok := service["<<"]; ok {
+		t.Error("Expected merge key to be expanded")
+	}
+	if string(repo.GetRawData()) != content {
+		t.Errorf("Expected raw data to keep anchors, got: %s", string(repo.GetRawData()))
+	}
+}
+
+// TestFileRepositoryVerifyMergeKeys tests that a quoted merge key is rejected only when verification is enabled
+func TestFileRepositoryVerifyMergeKeys(t *testing.T) {
+	path := writeConfig(t, "quoted.yaml", "service:\n  \"<<\": {timeout: 30}\n")
+
+	repo := &FileRepository{Name: "quoted", Path: path}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error without verification, got: %v", err)
+	}
+
+	repo = &FileRepository{Name: "quoted", Path: path, DecodeOptions: DecodeOptions{VerifyMergeKeys: true}}
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error for unexpanded merge key")
+	}
+	if !strings.Contains(err.Error(), "service.<<") {
+		t.Errorf("Expected error to name the key path, got: %v", err)
+	}
+	if _, ok := repo.GetData("service"); ok {
+		t.Error("Expected data not to be swapped in after a failed verification")
+	}
+}
+
+// TestFileRepositoryGetAllDataIsDeepCopy tests that mutating the returned map does not affect later reads
+func TestFileRepositoryGetAllDataIsDeepCopy(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "database:\n  host: localhost\n  replicas: [a, b]\n")
+	repo := &FileRepository{Name: "test", Path: path}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	all := repo.GetAllData()
+	db := all["database"].(map[string]interface{})
+	db["host"] = "mutated"
+	db["replicas"].([]interface{})[0] = "mutated"
+
+	val, _ := repo.GetData("database")
+	db = val.(map[string]interface{})
+	if db["host"] != "localhost" {
+		t.Errorf("Expected host 'localhost', got '%v'", db["host"])
+	}
+	if db["replicas"].([]interface{})[0] != "a" {
+		t.Errorf("Expected first replica 'a', got '%v'", db["replicas"].([]interface{})[0])
+	}
+}
+
+// writeFiles writes each named file into a shared temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestFileRepositoryInclude tests that !include splices in other files relative to the including file
+func TestFileRepositoryInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.yaml":            "name: app\ndatabase: !include database.yaml\nfeatures: !include features/flags.yaml\n",
+		"database.yaml":        "host: localhost\nport: 5432\n",
+		"features/flags.yaml":  "beta: true\nlimits: !include limits.yaml\n",
+		"features/limits.yaml": "rps: 100\n",
+	})
+	repo := &FileRepository{Name: "include", Path: filepath.Join(dir, "root.yaml")}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("name"); val != "app" {
+		t.Errorf("Expected name 'app', got '%v'", val)
+	}
+	val, _ := repo.GetData("database")
+	database, ok := val.(map[string]interface{})
+	if !ok || database["host"] != "localhost" || database["port"] != 5432 {
+		t.Errorf("Expected included database config, got %v", val)
+	}
+	val, _ = repo.GetData("features")
+	features, ok := val.(map[string]interface{})
+	if !ok || features["beta"] != true {
+		t.Fatalf("Expected included features config, got %v", val)
+	}
+	limits, ok := features["limits"].(map[string]interface{})
+	if !ok || limits["rps"] != 100 {
+		t.Errorf("Expected nested include relative to features/, got %v", features["limits"])
+	}
+	if strings.Contains(string(repo.GetRawData()), "!include") {
+		t.Errorf("Expected raw data to be expanded, got: %s", string(repo.GetRawData()))
+	}
+}
+
+// TestFileRepositoryIncludeCycle tests that include cycles are reported instead of recursing forever
+func TestFileRepositoryIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "b: !include b.yaml\n",
+		"b.yaml": "a: !include a.yaml\n",
+	})
+	repo := &FileRepository{Name: "cycle", Path: filepath.Join(dir, "a.yaml")}
+
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error for include cycle")
+	}
+	if !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got: %v", err)
+	}
+}
+
+// TestFileRepositoryValidator tests that a rejecting Validator fails the refresh and keeps the old data
+func TestFileRepositoryValidator(t *testing.T) {
+	errNegative := errors.New("timeout must be positive")
+	path := writeConfig(t, "config.yaml", "timeout: 30\n")
+	repo := &FileRepository{Name: "test", Path: path, DecodeOptions: DecodeOptions{
+		Validator: func(data map[string]interface{}) error {
+			if timeout, ok := data["timeout"].(int); !ok || timeout <= 0 {
+				return errNegative
+			}
+			return nil
+		},
+	}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("timeout: -1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	err := repo.Refresh()
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("Expected validator error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected error to name the source, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected old data to survive, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "timeout: 30\n" {
+		t.Errorf("Expected old raw data to survive, got %q", raw)
+	}
+}
+
+// TestFileRepositoryMultipleDocuments tests that documents are merged, empty documents are skipped,
+// and a document that is not a mapping fails the refresh
+func TestFileRepositoryMultipleDocuments(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "a: 1\nb: 1\n---\n---\nb: 2\n")
+	repo := &FileRepository{Name: "test", Path: path}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("a"); val != 1 {
+		t.Errorf("Expected a = 1, got %v", val)
+	}
+	if val, _ := repo.GetData("b"); val != 2 {
+		t.Errorf("Expected b = 2, got %v", val)
+	}
+
+	if err := os.WriteFile(path, []byte("a: 1\n---\n- not a map\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := repo.Refresh(); err == nil {
+		t.Error("Expected error for a document that is not a mapping")
+	}
+}
+
+// TestFileRepositoryErrorKinds tests that refresh errors are tagged with their kind
+func TestFileRepositoryErrorKinds(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"malformed", "list: [a, b\n", ErrParse},
+		{"missing include", "db: !include missing.yaml\n", ErrNotFound},
+		{"include cycle", "self: !include config.yaml\n", ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			repo := &FileRepository{Name: "test", Path: path}
+			if err := repo.Refresh(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	repo := &FileRepository{Name: "test", Path: filepath.Join(dir, "missing.yaml")}
+	err := repo.Refresh()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing file, got: %v", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the original error to stay in the chain, got: %v", err)
+	}
+}
+
+// TestFileRepositoryPreserveBigNumbers tests that numbers a float64 would round decode as
+// their literal text when PreserveBigNumbers is set
+func TestFileRepositoryPreserveBigNumbers(t *testing.T) {
+	content := "id: 303984756986439880155862132370440192\nprice: 0.1234567890123456789\ncount: 42\nratio: 0.5\nmax: 18446744073709551615\n"
+	path := writeConfig(t, "numbers.yaml", content)
+
+	repo := &FileRepository{Name: "numbers", Path: path, DecodeOptions: DecodeOptions{PreserveBigNumbers: true}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := map[string]interface{}{
+		"id":    "303984756986439880155862132370440192",
+		"price": "0.1234567890123456789",
+		"count": 42,
+		"ratio": 0.5,
+		"max":   uint64(18446744073709551615),
+	}
+	for key, expected := range want {
+		if val, _ := repo.GetData(key); val != expected {
+			t.Errorf("Expected %s to be %#v, got %#v", key, expected, val)
+		}
+	}
+
+	// Without the option big integers are rounded to float64
+	repo = &FileRepository{Name: "numbers", Path: path}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("id"); val != 3.0398475698643988e+35 {
+		t.Errorf("Expected id to be a float64, got %#v", val)
+	}
+}
+
+// TestFileRepositoryEmpty tests that empty and whitespace-only files keep the previous data under EmptyError
+func TestFileRepositoryEmpty(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":      "",
+		"whitespace": "  \n\n   \n",
+		"comments":   "# nothing here yet\n",
+	} {
+		path := writeConfig(t, "config.yaml", "key: value\n")
+		repo := &FileRepository{Name: "test", Path: path, DecodeOptions: DecodeOptions{OnEmpty: EmptyError}}
+		if err := repo.Refresh(); err != nil {
+			t.Fatalf("%s: Expected no error, got: %v", name, err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("%s: Failed to write config: %v", name, err)
+		}
+		err := repo.Refresh()
+		if !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "empty") {
+			t.Errorf("%s: Expected an empty config ErrParse, got: %v", name, err)
+		}
+		if val, _ := repo.GetData("key"); val != "value" {
+			t.Errorf("%s: Expected previous data to be kept, got %v", name, val)
+		}
+		if string(repo.GetRawData()) != "key: value\n" {
+			t.Errorf("%s: Expected previous raw data to be kept, got %q", name, repo.GetRawData())
+		}
+
+		// The default only warns and serves the empty config
+		repo.OnEmpty = EmptyWarn
+		if err := repo.Refresh(); err != nil {
+			t.Errorf("%s: Expected no error with EmptyWarn, got: %v", name, err)
+		}
+		if _, ok := repo.GetData("key"); ok {
+			t.Errorf("%s: Expected the empty config to be served with EmptyWarn", name)
+		}
+	}
+}
+
+// TestFileRepositoryRootKey tests that a nested sub-map is mounted as the repository's data
+func TestFileRepositoryRootKey(t *testing.T) {
+	content := "teams:\n  payments:\n    timeout: 30\n    retries: 3\n  search:\n    timeout: 5\nversion: 2\n"
+	path := writeConfig(t, "shared.yaml", content)
+
+	repo := &FileRepository{Name: "payments", Path: path, DecodeOptions: DecodeOptions{RootKey: "teams.payments"}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected timeout 30, got %v", val)
+	}
+	for _, key := range []string{"teams", "search", "version"} {
+		if _, ok := repo.GetData(key); ok {
+			t.Errorf("Expected %s to be outside the root", key)
+		}
+	}
+	if string(repo.GetRawData()) != "retries: 3\ntimeout: 30\n" {
+		t.Errorf("Expected raw data to be the selected sub-document, got %q", repo.GetRawData())
+	}
+
+	tests := []struct {
+		rootKey string
+		kind    error
+	}{
+		{"teams.billing", ErrNotFound},
+		{"teams.payments.timeout", ErrParse},
+		{"version", ErrParse},
+	}
+	for _, tt := range tests {
+		repo := &FileRepository{Name: "bad", Path: path, DecodeOptions: DecodeOptions{RootKey: tt.rootKey}}
+		if err := repo.Refresh(); !errors.Is(err, tt.kind) || !strings.Contains(err.Error(), tt.rootKey) {
+			t.Errorf("%s: Expected %v naming the root key, got: %v", tt.rootKey, tt.kind, err)
+		}
+	}
+}
+
+// TestFileRepositoryTransforms tests that byte transforms run in order before decoding,
+// map transforms after, and a failing transform keeps the previous data
+func TestFileRepositoryTransforms(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "region: \"@region@\"\ntimeout: 30\n")
+	var failBytes, failMap bool
+	repo := &FileRepository{Name: "test", Path: path, DecodeOptions: DecodeOptions{
+		Transforms: []func([]byte) ([]byte, error){
+			func(data []byte) ([]byte, error) {
+				return bytes.ReplaceAll(data, []byte("@region@"), []byte("eu-@zone@")), nil
+			},
+			func(data []byte) ([]byte, error) {
+				if failBytes {
+					return nil, errors.New("zone lookup failed")
+				}
+				return bytes.ReplaceAll(data, []byte("@zone@"), []byte("west")), nil
+			},
+		},
+		MapTransforms: []func(map[string]interface{}) error{
+			func(data map[string]interface{}) error {
+				if failMap {
+					return errors.New("missing timeout")
+				}
+				data["timeout_ms"] = data["timeout"].(int) * 1000
+				return nil
+			},
+		},
+	}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("region"); val != "eu-west" {
+		t.Errorf("Expected the byte transforms to run in order, got %v", val)
+	}
+	if val, _ := repo.GetData("timeout_ms"); val != 30000 {
+		t.Errorf("Expected the map transform to add timeout_ms, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "region: \"eu-west\"\ntimeout: 30\n" {
+		t.Errorf("Expected the transformed document as raw data, got %q", raw)
+	}
+
+	if err := os.WriteFile(path, []byte("region: \"@region@\"\ntimeout: 60\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	failBytes = true
+	if err := repo.Refresh(); !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "zone lookup failed") {
+		t.Errorf("Expected the byte transform error tagged ErrParse, got: %v", err)
+	}
+	failBytes, failMap = false, true
+	if err := repo.Refresh(); err == nil || !strings.Contains(err.Error(), "missing timeout") {
+		t.Errorf("Expected the map transform error, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected old data to survive, got %v", val)
+	}
+
+	failMap = false
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout_ms"); val != 60000 {
+		t.Errorf("Expected the new data once the transforms succeed, got %v", val)
+	}
+}
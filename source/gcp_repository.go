@@ -4,8 +4,11 @@ import (
 	// ...
 	"cloud.google.com/go/storage"
 	"context"
-	"gopkg.in/yaml.v3"
+	"errors"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"google.golang.org/api/googleapi"
 	"io"
+	"strings"
 	"sync"
 	// ...
 )
@@ -13,25 +16,44 @@ import (
 // GcpStorageRepository is a struct that implements the Repository interface for
 // handling configuration data stored in a YAML file within a GCS bucket.
 type GcpStorageRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name          string                // Name of the configuration source
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
 	data          map[string]interface{} // Map to store the configuration data
-	BucketName    string                // Name of the GCS bucket
-	ObjectName    string                // Name of the YAML file within the GCS bucket
-	Client        *storage.Client       // GCS client instance
-	rawData       []byte                // Raw data of the YAML configuration file
-	clientOnce    sync.Once             // Ensures client is initialized only once
-	clientInitErr error                 // Stores error from client initialization
+	BucketName    string                 // Name of the GCS bucket
+	ObjectName    string                 // Name of the YAML file within the GCS bucket
+	ObjectNames   []string               // Several YAML files merged in order, used instead of ObjectName when set
+	Archive       bool                   // Objects are .tar.gz, .tar or .zip bundles whose YAML and JSON files are merged
+	Client        *storage.Client        // GCS client instance
+	rawData       []byte                 // Raw data of the YAML configuration file
+	clientOnce    sync.Once              // Ensures client is initialized only once
+	clientInitErr error                  // Stores error from client initialization
 }
 
 // Refresh reads the YAML file from the GCS bucket, unmarshal it into the data map.
+// With ObjectNames, every object is read and their top-level keys are merged
+// in order, later objects taking precedence; GetRawData then returns the
+// merged document. With Archive, each object is a bundle whose configuration
+// files are merged in lexical order of their paths.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
 func (g *GcpStorageRepository) Refresh() error {
+	return g.cached(g, g.DecodeOptions, "gs://"+g.BucketName+"/"+strings.Join(objectNames(g.ObjectName, g.ObjectNames), ","), g.refresh, func(data map[string]interface{}, rawData []byte) {
+		g.Lock()
+		g.data, g.rawData = data, rawData
+		g.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (g *GcpStorageRepository) refresh() error {
 	ctx := context.Background()
 
 	// Thread-safe client initialization using sync.Once (only if client not pre-configured)
 	if g.Client == nil {
 		g.clientOnce.Do(func() {
-			g.Client, g.clientInitErr = storage.NewClient(ctx)
+			g.Client, g.clientInitErr = NewSharedGCSClient(ctx)
 		})
 		if g.clientInitErr != nil {
 			return g.clientInitErr
@@ -39,23 +61,24 @@ func (g *GcpStorageRepository) Refresh() error {
 	}
 
 	// Network I/O outside lock for better performance
-	bucket := g.Client.Bucket(g.BucketName)
-	obj := bucket.Object(g.ObjectName)
-	reader, err := obj.NewReader(ctx)
-	if err != nil {
-		return err
+	var objects []object
+	for _, name := range objectNames(g.ObjectName, g.ObjectNames) {
+		fileContent, err := g.readObject(ctx, name)
+		if err != nil {
+			return withKind(gcsKind(err), err)
+		}
+		objects = append(objects, object{location: "gs://" + g.BucketName + "/" + name, content: fileContent})
 	}
-	defer reader.Close()
 
-	// Read the file content from the reader.
-	fileContent, err := io.ReadAll(reader)
-	if err != nil {
-		return err
+	if g.Archive {
+		var err error
+		if objects, err = expandArchives(g.Name, objects); err != nil {
+			return err
+		}
 	}
 
 	// Unmarshal to temp variable outside lock to prevent data corruption on error
-	var tempData map[string]interface{}
-	err = yaml.Unmarshal(fileContent, &tempData)
+	tempData, fileContent, err := g.decodeObjects(g.Name, objects)
 	if err != nil {
 		return err
 	}
@@ -69,11 +92,49 @@ func (g *GcpStorageRepository) Refresh() error {
 	return nil
 }
 
+// NewSharedGCSClient creates a GCS client from the default credentials.
+// Repositories without a Client each create their own on first refresh; set
+// Client to one shared client instead when many repositories read the same
+// project, so they share one connection pool. The shared client is safe for
+// concurrent use and must outlive the repositories using it.
+func NewSharedGCSClient(ctx context.Context) (*storage.Client, error) {
+	return storage.NewClient(ctx)
+}
+
+// gcsKind maps a GCS error to an error kind, or nil if it has none.
+func gcsKind(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) || errors.Is(err, storage.ErrBucketNotExist) {
+		return ErrNotFound
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return statusKind(apiErr.Code)
+	}
+	return networkKind(err)
+}
+
+// readObject reads the content of one object in the bucket.
+func (g *GcpStorageRepository) readObject(ctx context.Context, name string) ([]byte, error) {
+	reader, err := g.Client.Bucket(g.BucketName).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Read the file content from the reader.
+	return io.ReadAll(reader)
+}
+
 // GetName returns the name of the configuration source.
 func (g *GcpStorageRepository) GetName() string {
 	return g.Name
 }
 
+// Type returns the repository type.
+func (g *GcpStorageRepository) Type() string {
+	return TypeGcpStorage
+}
+
 // GetData returns the configuration data as a map of configuration names to their respective models.
 func (g *GcpStorageRepository) GetData(configName string) (config interface{}, isPresent bool) {
 	g.RLock()
@@ -82,6 +143,20 @@ func (g *GcpStorageRepository) GetData(configName string) (config interface{}, i
 	return config, isPresent
 }
 
+// GetAllData returns a deep copy of the decoded configuration map.
+func (g *GcpStorageRepository) GetAllData() map[string]interface{} {
+	g.RLock()
+	defer g.RUnlock()
+	return deepcopy.Map(g.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (g *GcpStorageRepository) Keys() []string {
+	g.RLock()
+	defer g.RUnlock()
+	return mapKeys(g.data)
+}
+
 // GetRawData returns the raw data of the YAML configuration file.
 func (g *GcpStorageRepository) GetRawData() []byte {
 	g.RLock()
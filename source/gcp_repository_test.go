@@ -0,0 +1,322 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/fullstorydev/emulators/storage/gcsemu"
+	"gopkg.in/yaml.v3"
+)
+
+// newGcsBucket starts an in-memory GCS emulator with a bucket holding objects.
+func newGcsBucket(t *testing.T, bucketName string, objects map[string]string) *storage.Client {
+	t.Helper()
+	svr, err := gcsemu.NewServer("127.0.0.1:0", gcsemu.Options{})
+	if err != nil {
+		t.Fatalf("Failed to start storage emulator: %v", err)
+	}
+	t.Cleanup(svr.Close)
+	t.Setenv("STORAGE_EMULATOR_HOST", svr.Addr)
+
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to create storage client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	createGcsBucket(t, client, bucketName, objects)
+	return client
+}
+
+// createGcsBucket creates a bucket holding objects through client.
+func createGcsBucket(t *testing.T, client *storage.Client, bucketName string, objects map[string]string) {
+	t.Helper()
+	ctx := context.Background()
+	bucket := client.Bucket(bucketName)
+	if err := bucket.Create(ctx, "test-project", nil); err != nil {
+		t.Fatalf("Failed to create bucket: %v", err)
+	}
+	for name, content := range objects {
+		w := bucket.Object(name).NewWriter(ctx)
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Failed to close %s: %v", name, err)
+		}
+	}
+}
+
+// TestGcpStorageRepositoryObjectNames tests that several objects are merged into one repository
+func TestGcpStorageRepositoryObjectNames(t *testing.T) {
+	client := newGcsBucket(t, "config", map[string]string{
+		"flags.yaml":  "dark_mode: true\ntimeout: 10\n",
+		"routes.yaml": "routes:\n  - /api\ntimeout: 30\n",
+	})
+	repo := &GcpStorageRepository{
+		Name:        "merged",
+		BucketName:  "config",
+		ObjectNames: []string{"flags.yaml", "routes.yaml"},
+		Client:      client,
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if val, _ := repo.GetData("dark_mode"); val != true {
+		t.Errorf("Expected dark_mode from flags.yaml, got %v", val)
+	}
+	if val, ok := repo.GetData("routes"); !ok || len(val.([]interface{})) != 1 {
+		t.Errorf("Expected routes from routes.yaml, got %v", val)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected later object to override timeout, got %v", val)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(repo.GetRawData(), &raw); err != nil {
+		t.Fatalf("Expected raw data to be a YAML document, got error: %v", err)
+	}
+	if len(raw) != 3 || raw["timeout"] != 30 {
+		t.Errorf("Expected raw data to be the merged document, got %v", raw)
+	}
+}
+
+// TestGcpStorageRepositoryMissingObject tests that a missing object fails the refresh
+func TestGcpStorageRepositoryMissingObject(t *testing.T) {
+	client := newGcsBucket(t, "config", map[string]string{"flags.yaml": "dark_mode: true\n"})
+	repo := &GcpStorageRepository{
+		Name:        "merged",
+		BucketName:  "config",
+		ObjectNames: []string{"flags.yaml", "missing.yaml"},
+		Client:      client,
+	}
+	if err := repo.Refresh(); err == nil {
+		t.Error("Expected error for missing object")
+	}
+	if _, ok := repo.GetData("dark_mode"); ok {
+		t.Error("Expected no partial data after a failed refresh")
+	}
+}
+
+// TestGcpStorageRepositoryMalformedKeepsData tests that a malformed object does not replace the last good data
+func TestGcpStorageRepositoryMalformedKeepsData(t *testing.T) {
+	client := newGcsBucket(t, "config", map[string]string{"config.yaml": "key: value\n"})
+	repo := &GcpStorageRepository{Name: "test", BucketName: "config", ObjectName: "config.yaml", Client: client}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	w := client.Bucket("config").Object("config.yaml").NewWriter(context.Background())
+	if _, err := w.Write([]byte("key: [value\n")); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close config.yaml: %v", err)
+	}
+	if err := repo.Refresh(); !errors.Is(err, ErrParse) {
+		t.Fatalf("Expected a parse error, got: %v", err)
+	}
+	if val, _ := repo.GetData("key"); val != "value" {
+		t.Errorf("Expected the last good data to survive, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "key: value\n" {
+		t.Errorf("Expected the last good raw data to survive, got %q", raw)
+	}
+}
+
+// TestDecodeObjectsSingle tests that a single object keeps its content as raw data
+func TestDecodeObjectsSingle(t *testing.T) {
+	content := []byte("# comment\nkey: value\n")
+	data, raw, err := DecodeOptions{}.decodeObjects("single", []object{{location: "s3://bucket/a.yaml", content: content}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data["key"] != "value" || string(raw) != string(content) {
+		t.Errorf("Expected object decoded as-is, got %v / %q", data, raw)
+	}
+}
+
+// TestDecodeObjectsRejectsSops tests that SOPS-encrypted objects are not merged into plaintext
+func TestDecodeObjectsRejectsSops(t *testing.T) {
+	objects := []object{
+		{location: "s3://bucket/a.yaml", content: []byte("key: value\n")},
+		{location: "s3://bucket/b.yaml", content: []byte("secret: ENC[x]\nsops:\n  version: 3.9.0\n")},
+	}
+	_, _, err := DecodeOptions{DecryptSops: true}.decodeObjects("merged", objects)
+	if err == nil || !strings.Contains(err.Error(), "b.yaml") {
+		t.Errorf("Expected error naming the encrypted object, got: %v", err)
+	}
+}
+
+// TestDecodeObjectsValidatesMerged tests that the Validator sees the merged data once
+func TestDecodeObjectsValidatesMerged(t *testing.T) {
+	var calls int
+	opts := DecodeOptions{Validator: func(data map[string]interface{}) error {
+		calls++
+		if _, ok := data["a"]; !ok {
+			return errors.New("a is required")
+		}
+		if _, ok := data["b"]; !ok {
+			return errors.New("b is required")
+		}
+		return nil
+	}}
+	objects := []object{
+		{location: "gs://bucket/a.yaml", content: []byte("a: 1\n")},
+		{location: "gs://bucket/b.yaml", content: []byte("b: 2\n")},
+	}
+	if _, _, err := opts.decodeObjects("merged", objects); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected validator to run once, got %d", calls)
+	}
+	if _, _, err := opts.decodeObjects("merged", objects[:1]); err == nil {
+		t.Error("Expected validator error for a single object missing b")
+	}
+}
+
+// TestGcpStorageRepositorySharedClient tests that repositories sharing one client reuse its connections
+func TestGcpStorageRepositorySharedClient(t *testing.T) {
+	mux := http.NewServeMux()
+	gcsemu.NewGcsEmu(gcsemu.Options{}).Register(mux)
+	var conns atomic.Int32
+	svr := httptest.NewUnstartedServer(mux)
+	svr.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	svr.Start()
+	defer svr.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(svr.URL, "http://"))
+
+	client, err := NewSharedGCSClient(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer client.Close()
+	createGcsBucket(t, client, "config", map[string]string{
+		"flags.yaml":  "dark_mode: true\n",
+		"routes.yaml": "routes:\n  - /api\n",
+	})
+
+	flags := &GcpStorageRepository{Name: "flags", BucketName: "config", ObjectName: "flags.yaml", Client: client}
+	routes := &GcpStorageRepository{Name: "routes", BucketName: "config", ObjectName: "routes.yaml", Client: client}
+	for i := 0; i < 3; i++ {
+		for _, repo := range []*GcpStorageRepository{flags, routes} {
+			if err := repo.Refresh(); err != nil {
+				t.Fatalf("Expected no error refreshing %s, got: %v", repo.Name, err)
+			}
+		}
+	}
+
+	if val, _ := flags.GetData("dark_mode"); val != true {
+		t.Errorf("Expected dark_mode from flags.yaml, got %v", val)
+	}
+	if _, ok := routes.GetData("routes"); !ok {
+		t.Error("Expected routes from routes.yaml")
+	}
+	if flags.Client != client || routes.Client != client {
+		t.Error("Expected repositories to keep the shared client")
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("Expected every request to reuse one connection, got %d connections", n)
+	}
+}
+
+// TestGcpStorageRepositoryNotFound tests that a missing object is reported as ErrNotFound
+func TestGcpStorageRepositoryNotFound(t *testing.T) {
+	client := newGcsBucket(t, "config", map[string]string{"flags.yaml": "dark_mode: true\n"})
+	repo := &GcpStorageRepository{Name: "missing", BucketName: "config", ObjectName: "missing.yaml", Client: client}
+
+	err := repo.Refresh()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+	if !errors.Is(err, storage.ErrObjectNotExist) {
+		t.Errorf("Expected the original error to stay in the chain, got: %v", err)
+	}
+}
+
+// TestGcpStorageRepositoryArchive tests that the files of a .tar.gz object are merged in path order
+func TestGcpStorageRepositoryArchive(t *testing.T) {
+	bundle := tarGzBytes(t, [][2]string{
+		{"config/20-overrides.yaml", "timeout: 30\n"},
+		{"config/10-base.yaml", "timeout: 10\ndark_mode: true\n"},
+		{"config/limits.json", `{"max_users": 100}`},
+	})
+	client := newGcsBucket(t, "config", map[string]string{"bundle.tar.gz": string(bundle)})
+	repo := &GcpStorageRepository{
+		Name:       "bundle",
+		BucketName: "config",
+		ObjectName: "bundle.tar.gz",
+		Archive:    true,
+		Client:     client,
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := map[string]interface{}{"timeout": 30, "dark_mode": true, "max_users": 100}
+	for key, expected := range want {
+		if val, _ := repo.GetData(key); val != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, val)
+		}
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(repo.GetRawData(), &raw); err != nil || raw["timeout"] != 30 {
+		t.Errorf("Expected raw data to be the merged document, got %q", repo.GetRawData())
+	}
+}
+
+// TestDecodeObjectsRootKey tests that the root key is selected from the merged objects
+func TestDecodeObjectsRootKey(t *testing.T) {
+	objects := []object{
+		{location: "gs://config/base.yaml", content: []byte("payments:\n  timeout: 10\n")},
+		{location: "gs://config/override.yaml", content: []byte("other: true\n")},
+	}
+	data, raw, err := DecodeOptions{RootKey: "payments"}.decodeObjects("merged", objects)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data["timeout"] != 10 || len(data) != 1 {
+		t.Errorf("Expected only the payments keys, got %v", data)
+	}
+	if string(raw) != "timeout: 10\n" {
+		t.Errorf("Expected raw data to be the selected sub-document, got %q", raw)
+	}
+}
+
+// TestDecodeObjectsMapTransforms tests that map transforms run once on the merged objects
+func TestDecodeObjectsMapTransforms(t *testing.T) {
+	objects := []object{
+		{location: "gs://config/base.yaml", content: []byte("a: 1\n")},
+		{location: "gs://config/override.yaml", content: []byte("b: 2\n")},
+	}
+	calls := 0
+	options := DecodeOptions{MapTransforms: []func(map[string]interface{}) error{
+		func(data map[string]interface{}) error {
+			calls++
+			data["keys"] = len(data)
+			return nil
+		},
+	}}
+	data, raw, err := options.decodeObjects("merged", objects)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 1 || data["keys"] != 2 {
+		t.Errorf("Expected one call seeing both objects, got %d calls and %v", calls, data)
+	}
+	if string(raw) != "a: 1\nb: 2\nkeys: 2\n" {
+		t.Errorf("Expected raw data re-encoded from the transformed map, got %q", raw)
+	}
+}
@@ -2,6 +2,7 @@ package source
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -12,18 +13,23 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 // GitRepository is a struct that implements the Repository interface for
 // handling configuration data stored in a YAML file within a Git repository.
 // Deprecated: This is Deprecated because it there is API limitation you make to github and gitlab. Which will get exhausted.
 // This is not a good way to handle the configuration is to use your CI to upload the configuration to a S3/GCS bucket and then use the S3/GCS  repository to fetch the configuration.
+// To read a single file from GitHub, use GitHubFileRepository instead.
 type GitRepository struct {
 	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
 	Name          string                 // Name of the configuration source
 	data          map[string]interface{} // Map to store the configuration data
 	URL           *url.URL               // URL representing the Git repository URL
@@ -42,6 +48,25 @@ func (g *GitRepository) GetName() string {
 	return g.Name
 }
 
+// Type returns the repository type.
+func (g *GitRepository) Type() string {
+	return TypeGit
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (g *GitRepository) GetAllData() map[string]interface{} {
+	g.RLock()
+	defer g.RUnlock()
+	return deepcopy.Map(g.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (g *GitRepository) Keys() []string {
+	g.RLock()
+	defer g.RUnlock()
+	return mapKeys(g.data)
+}
+
 // GetRawData returns the raw data of the YAML configuration file.
 func (g *GitRepository) GetRawData() []byte {
 	g.RLock()
@@ -50,7 +75,17 @@ func (g *GitRepository) GetRawData() []byte {
 }
 
 // Refresh reads the YAML file from the Git repository, unmarshal it into the data map.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
 func (g *GitRepository) Refresh() error {
+	return g.cached(g, g.DecodeOptions, g.URL.Redacted()+"@"+g.Branch+":"+g.Path, g.refresh, func(data map[string]interface{}, rawData []byte) {
+		g.Lock()
+		g.data, g.rawData = data, rawData
+		g.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (g *GitRepository) refresh() error {
 	ctx := context.Background()
 
 	// Thread-safe clone using sync.Once (only first call clones)
@@ -95,7 +130,7 @@ func (g *GitRepository) Refresh() error {
 		g.gitRepository = r
 	})
 	if g.cloneErr != nil {
-		return g.cloneErr
+		return withKind(gitKind(g.cloneErr), g.cloneErr)
 	}
 
 	// Pull latest changes (no lock needed - idempotent operation)
@@ -119,7 +154,7 @@ func (g *GitRepository) Refresh() error {
 
 	err = w.PullContext(ctx, pullOptions)
 	if err != nil && err != git.NoErrAlreadyUpToDate {
-		return err
+		return withKind(gitKind(err), err)
 	}
 	if err == git.NoErrAlreadyUpToDate {
 		logrus.Debug("Already up to date")
@@ -130,7 +165,7 @@ func (g *GitRepository) Refresh() error {
 	// Read the config file
 	file, err := g.fs.Open(g.Path)
 	if err != nil {
-		return fmt.Errorf("error opening file %s: %w", g.Path, err)
+		return withKind(fileKind(err), fmt.Errorf("error opening file %s: %w", g.Path, err))
 	}
 	defer file.Close()
 
@@ -140,8 +175,7 @@ func (g *GitRepository) Refresh() error {
 	}
 
 	// Unmarshal to temp variable outside lock to prevent data corruption on error
-	var tempData map[string]interface{}
-	err = yaml.Unmarshal(fileContent, &tempData)
+	tempData, fileContent, err := g.decode(g.Name, g.URL.Redacted()+":"+g.Path, fileContent)
 	if err != nil {
 		logrus.Debug("error unmarshalling file")
 		return err
@@ -165,3 +199,15 @@ func (g *GitRepository) GetData(configName string) (config interface{}, isPresen
 	config, isPresent = g.data[configName]
 	return config, isPresent
 }
+
+// gitKind maps a clone or pull error to an error kind, or nil if it has none.
+func gitKind(err error) error {
+	switch {
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		return ErrNotFound
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed):
+		return ErrPermission
+	default:
+		return networkKind(err)
+	}
+}
@@ -0,0 +1,185 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultGitHubAPIURL is the GitHub REST API used when APIURL is not set.
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubFileRepository is a struct that implements the Repository interface
+// for a single YAML file in a GitHub repository, read through the contents
+// API instead of a clone. Refreshes are conditional requests on the file's
+// ETag, so an unchanged file is not downloaded again and, on GitHub, does
+// not count against the API rate limit. It is a lightweight replacement for
+// the deprecated GitRepository.
+type GitHubFileRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
+	Owner         string                 // Owner (user or organization) of the GitHub repository
+	Repo          string                 // Name of the GitHub repository
+	Path          string                 // Path to the YAML file within the repository
+	Ref           string                 // Branch, tag or commit to read, defaults to the default branch
+	Token         string                 // Optional token sent as a bearer token, required for private repositories
+	APIURL        *url.URL               // Optional API base URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise
+	data          map[string]interface{} // Map to store the configuration data
+	rawData       []byte                 // Raw data of the YAML configuration file
+	etag          string                 // ETag of the response rawData was decoded from, sent as If-None-Match
+}
+
+// GetName returns the name of the configuration source.
+func (g *GitHubFileRepository) GetName() string {
+	return g.Name
+}
+
+// Type returns the repository type.
+func (g *GitHubFileRepository) Type() string {
+	return TypeGitHub
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (g *GitHubFileRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	g.RLock()
+	defer g.RUnlock()
+	config, isPresent = g.data[configName]
+	return config, isPresent
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (g *GitHubFileRepository) GetAllData() map[string]interface{} {
+	g.RLock()
+	defer g.RUnlock()
+	return deepcopy.Map(g.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (g *GitHubFileRepository) Keys() []string {
+	g.RLock()
+	defer g.RUnlock()
+	return mapKeys(g.data)
+}
+
+// GetRawData returns the raw data of the YAML configuration file.
+func (g *GitHubFileRepository) GetRawData() []byte {
+	g.RLock()
+	defer g.RUnlock()
+	return g.rawData
+}
+
+// contentsURL returns the contents API URL of the file.
+func (g *GitHubFileRepository) contentsURL() string {
+	base := defaultGitHubAPIURL
+	if g.APIURL != nil {
+		base = strings.TrimSuffix(g.APIURL.String(), "/")
+	}
+	contents := fmt.Sprintf("%s/repos/%s/%s/contents/%s", base,
+		url.PathEscape(g.Owner), url.PathEscape(g.Repo), escapePath(g.Path))
+	if g.Ref != "" {
+		contents += "?ref=" + url.QueryEscape(g.Ref)
+	}
+	return contents
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Refresh fetches the file from the contents API, unmarshal it into the data
+// map. The file is requested in its raw form with the ETag of the last
+// response, and a 304 Not Modified keeps the current data.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
+func (g *GitHubFileRepository) Refresh() error {
+	return g.cached(g, g.DecodeOptions, g.contentsURL(), g.refresh, func(data map[string]interface{}, rawData []byte) {
+		g.Lock()
+		g.data, g.rawData = data, rawData
+		g.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (g *GitHubFileRepository) refresh() error {
+	location := fmt.Sprintf("github.com/%s/%s/%s", g.Owner, g.Repo, strings.Trim(g.Path, "/"))
+	if g.Ref != "" {
+		location += "@" + g.Ref
+	}
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, g.contentsURL(), nil)
+	if err != nil {
+		logrus.Debug("error creating request")
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github.raw")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	g.RLock()
+	etag := g.etag
+	g.RUnlock()
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		logrus.Debug("error doing request")
+		return withKind(networkKind(err), err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logrus.WithError(err).Debug("error closing response body")
+		}
+	}(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && etag != "":
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		err := fmt.Errorf("repository %q: GitHub API rate limit exceeded reading %s", g.Name, location)
+		return withKind(ErrUnreachable, err)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		err := fmt.Errorf("repository %q: unexpected status %d reading %s", g.Name, resp.StatusCode, location)
+		return withKind(statusKind(resp.StatusCode), err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logrus.Debug("error reading file")
+		return withKind(networkKind(err), err)
+	}
+
+	// Unmarshal to temp variable outside lock to prevent data corruption on error
+	tempData, data, err := g.decode(g.Name, location, data)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	// Only lock for atomic data swap
+	g.Lock()
+	g.data = tempData
+	g.rawData = data
+	g.etag = resp.Header.Get("ETag")
+	g.Unlock()
+
+	return nil
+}
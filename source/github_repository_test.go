@@ -0,0 +1,116 @@
+package source
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// newGitHubContentsAPI serves files from a fake GitHub contents API, answering
+// If-None-Match with 304 like GitHub does. It returns the API URL and a
+// function reporting how many times a file body was sent.
+func newGitHubContentsAPI(t *testing.T, token string, files map[string]string) (*url.URL, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Accept") != "application/vnd.github.raw" {
+			http.Error(w, "expected raw media type", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		content, ok := files[r.URL.Path+"?"+r.URL.RawQuery]
+		if !ok {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		etag := `"` + ContentHash([]byte(content)) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	apiURL, _ := url.Parse(server.URL + "/api/v3/")
+	return apiURL, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return downloads
+	}
+}
+
+// TestGitHubFileRepositoryRefresh tests that the file is read at the ref and not downloaded again while unchanged
+func TestGitHubFileRepositoryRefresh(t *testing.T) {
+	apiURL, downloads := newGitHubContentsAPI(t, "secret", map[string]string{
+		"/api/v3/repos/acme/config/contents/teams/payments.yaml?ref=release": "timeout: 30\n",
+	})
+	repo := &GitHubFileRepository{
+		Name:   "payments",
+		Owner:  "acme",
+		Repo:   "config",
+		Path:   "teams/payments.yaml",
+		Ref:    "release",
+		Token:  "secret",
+		APIURL: apiURL,
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := repo.Refresh(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected timeout 30, got %v", val)
+	}
+	if string(repo.GetRawData()) != "timeout: 30\n" {
+		t.Errorf("Expected raw data to be the file, got %q", repo.GetRawData())
+	}
+	if got := downloads(); got != 1 {
+		t.Errorf("Expected the file to be downloaded once, got %d", got)
+	}
+	if repo.Type() != TypeGitHub {
+		t.Errorf("Expected type %s, got %s", TypeGitHub, repo.Type())
+	}
+}
+
+// TestGitHubFileRepositoryErrorKinds tests that API errors are tagged with their kind
+func TestGitHubFileRepositoryErrorKinds(t *testing.T) {
+	apiURL, _ := newGitHubContentsAPI(t, "secret", map[string]string{
+		"/api/v3/repos/acme/config/contents/config.yaml?": "key: value\n",
+	})
+	tests := []struct {
+		name string
+		repo *GitHubFileRepository
+		kind error
+	}{
+		{"missing file", &GitHubFileRepository{Owner: "acme", Repo: "config", Path: "missing.yaml", Token: "secret", APIURL: apiURL}, ErrNotFound},
+		{"bad token", &GitHubFileRepository{Owner: "acme", Repo: "config", Path: "config.yaml", Token: "wrong", APIURL: apiURL}, ErrPermission},
+	}
+	for _, tt := range tests {
+		if err := tt.repo.Refresh(); !errors.Is(err, tt.kind) {
+			t.Errorf("%s: Expected %v, got: %v", tt.name, tt.kind, err)
+		}
+	}
+
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+	}))
+	defer limited.Close()
+	limitedURL, _ := url.Parse(limited.URL)
+	repo := &GitHubFileRepository{Name: "limited", Owner: "acme", Repo: "config", Path: "config.yaml", APIURL: limitedURL}
+	if err := repo.Refresh(); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected rate limiting to be ErrUnreachable, got: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package source
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHistorySize is the number of payloads a HistoryRepository keeps when
+// Size is not set.
+const defaultHistorySize = 10
+
+// HistoryEntry is one payload recorded by a HistoryRepository.
+type HistoryEntry struct {
+	Time    time.Time // When the refresh that fetched the payload completed
+	Hash    string    // ContentHash of RawData
+	RawData []byte    // Raw data returned by the wrapped repository
+}
+
+// HistoryRepository wraps another repository and keeps the raw data of its
+// last Size successful refreshes, so operators can see what the configuration
+// looked like a few refreshes ago. All Repository methods are forwarded to the
+// wrapped repository.
+type HistoryRepository struct {
+	Repository                // Wrapped repository
+	Labels                    // Optional labels reported in a config server's status; defaults to those of the wrapped repository
+	Size       int            // Number of entries to keep, defaults to 10
+	mu         sync.Mutex     // Protects entries and next
+	entries    []HistoryEntry // Ring buffer of recorded payloads
+	next       int            // Index the next entry is written to once the buffer is full
+}
+
+// Refresh refreshes the wrapped repository and records its raw data on success.
+func (h *HistoryRepository) Refresh() error {
+	if err := h.Repository.Refresh(); err != nil {
+		return err
+	}
+	raw := h.Repository.GetRawData()
+	entry := HistoryEntry{
+		Time:    time.Now(),
+		Hash:    ContentHash(raw),
+		RawData: raw,
+	}
+
+	size := h.Size
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) < size {
+		h.entries = append(h.entries, entry)
+		return nil
+	}
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	return nil
+}
+
+// History returns the recorded payloads, oldest first.
+func (h *HistoryRepository) History() []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]HistoryEntry, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	out = append(out, h.entries[:h.next]...)
+	return out
+}
+
+// GetAllData returns a deep copy of the wrapped repository's decoded
+// configuration map, see AllData.
+func (h *HistoryRepository) GetAllData() map[string]interface{} {
+	return AllData(h.Repository)
+}
+
+// Keys returns the wrapped repository's top-level configuration names, see
+// Keys.
+func (h *HistoryRepository) Keys() []string {
+	return Keys(h.Repository)
+}
@@ -0,0 +1,69 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestHistoryRepositoryRingBuffer tests that exactly Size entries are kept, oldest first
+func TestHistoryRepositoryRingBuffer(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "version: 0\n")
+	repo := &HistoryRepository{
+		Repository: &FileRepository{Name: "test", Path: path},
+		Size:       3,
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("version: %d\n", i)), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := repo.Refresh(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	history := repo.History()
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+	for i, entry := range history {
+		want := fmt.Sprintf("version: %d\n", i+3)
+		if string(entry.RawData) != want {
+			t.Errorf("Expected entry %d to be %q, got %q", i, want, string(entry.RawData))
+		}
+		if entry.Hash != ContentHash(entry.RawData) {
+			t.Errorf("Expected entry %d hash to match its data", i)
+		}
+		if i > 0 && entry.Time.Before(history[i-1].Time) {
+			t.Errorf("Expected entries in chronological order")
+		}
+	}
+
+	// Forwarded methods see the latest data
+	if val, _ := repo.GetData("version"); val != 5 {
+		t.Errorf("Expected version 5, got %v", val)
+	}
+	if repo.GetName() != "test" || repo.Type() != TypeFile {
+		t.Errorf("Expected name and type of the wrapped repository, got %s/%s", repo.GetName(), repo.Type())
+	}
+}
+
+// TestHistoryRepositorySkipsFailedRefresh tests that failed refreshes are not recorded
+func TestHistoryRepositorySkipsFailedRefresh(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "version: 1\n")
+	repo := &HistoryRepository{Repository: &FileRepository{Name: "test", Path: path}}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	if err := repo.Refresh(); err == nil {
+		t.Fatal("Expected error for missing file")
+	}
+	if len(repo.History()) != 1 {
+		t.Errorf("Expected 1 entry, got %d", len(repo.History()))
+	}
+}
@@ -0,0 +1,41 @@
+package source
+
+import "context"
+
+// HookRepository wraps another repository and calls BeforeRefresh and
+// AfterRefresh around each of its refreshes, so cross-cutting concerns such as
+// tracing spans, timing metrics or cache warming can be added without changing
+// the repository itself. All other Repository methods are forwarded to the
+// wrapped repository.
+type HookRepository struct {
+	Repository                                         // Wrapped repository
+	Labels                                             // Optional labels reported in a config server's status; defaults to those of the wrapped repository
+	BeforeRefresh func(ctx context.Context)            // Called before each refresh, optional
+	AfterRefresh  func(ctx context.Context, err error) // Called after each refresh with its error, optional
+}
+
+// Refresh calls BeforeRefresh, refreshes the wrapped repository, then calls
+// AfterRefresh with the result. The error is returned unchanged.
+func (h *HookRepository) Refresh() error {
+	ctx := context.Background()
+	if h.BeforeRefresh != nil {
+		h.BeforeRefresh(ctx)
+	}
+	err := h.Repository.Refresh()
+	if h.AfterRefresh != nil {
+		h.AfterRefresh(ctx, err)
+	}
+	return err
+}
+
+// GetAllData returns a deep copy of the wrapped repository's decoded
+// configuration map, see AllData.
+func (h *HookRepository) GetAllData() map[string]interface{} {
+	return AllData(h.Repository)
+}
+
+// Keys returns the wrapped repository's top-level configuration names, see
+// Keys.
+func (h *HookRepository) Keys() []string {
+	return Keys(h.Repository)
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestHookRepository tests that both hooks fire around each refresh and see its error
+func TestHookRepository(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "version: 1\n")
+	var calls []string
+	var lastErr error
+	repo := &HookRepository{
+		Repository: &FileRepository{Name: "test", Path: path},
+		BeforeRefresh: func(ctx context.Context) {
+			calls = append(calls, "before")
+		},
+		AfterRefresh: func(ctx context.Context, err error) {
+			calls = append(calls, "after")
+			lastErr = err
+		},
+	}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "before" || calls[1] != "after" {
+		t.Errorf("Expected before then after, got %v", calls)
+	}
+	if lastErr != nil {
+		t.Errorf("Expected AfterRefresh to see no error, got: %v", lastErr)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	err := repo.Refresh()
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got: %v", err)
+	}
+	if len(calls) != 4 {
+		t.Errorf("Expected hooks to fire on failure, got %v", calls)
+	}
+	if lastErr != err {
+		t.Errorf("Expected AfterRefresh to see %v, got %v", err, lastErr)
+	}
+
+	// Forwarded methods keep the last good data
+	if val, _ := repo.GetData("version"); val != 1 {
+		t.Errorf("Expected version 1, got %v", val)
+	}
+	if repo.GetName() != "test" || repo.Type() != TypeFile {
+		t.Errorf("Expected name and type of the wrapped repository, got %s/%s", repo.GetName(), repo.Type())
+	}
+}
+
+// TestHookRepositoryNilHooks tests that hooks are optional
+func TestHookRepositoryNilHooks(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "version: 1\n")
+	repo := &HookRepository{Repository: &FileRepository{Name: "test", Path: path}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeTag marks a scalar whose value is the path of a YAML file to splice
+// in place of the scalar, e.g. "database: !include database.yaml".
+const includeTag = "!include"
+
+// resolveIncludes parses the documents in data, read from path, and replaces
+// every !include node with the parsed content of the referenced file.
+// Relative include paths are resolved against the directory of the including
+// file, and included files may themselves include others. The returned bool
+// reports whether any include was found.
+func resolveIncludes(path string, data []byte) ([]*yaml.Node, bool, error) {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, false, withKind(ErrParse, fmt.Errorf("error decoding %s: %w", path, err))
+	}
+	// Skip the walk for the common case of a file without includes
+	if !bytes.Contains(data, []byte(includeTag)) {
+		return docs, false, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false, err
+	}
+	r := &includeResolver{stack: []string{abs}}
+	for _, doc := range docs {
+		if err := r.walk(doc, filepath.Dir(abs)); err != nil {
+			return nil, false, err
+		}
+	}
+	return docs, r.found, nil
+}
+
+// encodeDocuments encodes docs as a YAML stream.
+func encodeDocuments(docs []*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(4)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// includeResolver carries the chain of files currently being included, which
+// is used to detect cycles.
+type includeResolver struct {
+	stack []string
+	found bool
+}
+
+// walk resolves includes in node and its descendants. dir is the directory of
+// the file node was read from.
+func (r *includeResolver) walk(node *yaml.Node, dir string) error {
+	if node.Tag == includeTag {
+		return r.include(node, dir)
+	}
+	for _, child := range node.Content {
+		if err := r.walk(child, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// include replaces node, an !include scalar, with the root of the referenced
+// document.
+func (r *includeResolver) include(node *yaml.Node, dir string) error {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return withKind(ErrParse, fmt.Errorf("%s must be followed by a file path (line %d)", includeTag, node.Line))
+	}
+	r.found = true
+
+	path := node.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	for _, p := range r.stack {
+		if p == path {
+			return withKind(ErrParse, fmt.Errorf("include cycle: %s -> %s", strings.Join(r.stack, " -> "), path))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return withKind(fileKind(err), fmt.Errorf("error including %s: %w", path, err))
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return withKind(ErrParse, fmt.Errorf("error decoding %s: %w", path, err))
+	}
+
+	r.stack = append(r.stack, path)
+	err = r.walk(&doc, filepath.Dir(path))
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return err
+	}
+
+	if doc.Kind == 0 {
+		// An empty included file behaves like an explicit null
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		return nil
+	}
+	*node = *doc.Content[0]
+	return nil
+}
@@ -0,0 +1,33 @@
+package source
+
+// Labels tags a repository with key/value pairs, such as its owning team or
+// environment, that a config server reports in its status so operators can
+// group repositories without encoding that in their names. It is embedded in
+// the repositories in this package:
+//
+//	repo := &FileRepository{Name: "payments", Path: "payments.yaml",
+//		Labels: Labels{"team": "payments", "env": "prod"}}
+type Labels map[string]string
+
+// GetLabels returns the labels.
+func (l Labels) GetLabels() map[string]string {
+	return l
+}
+
+// LabelsOf returns the labels of r, or nil if it has none. A HookRepository
+// or HistoryRepository without labels of its own reports the labels of the
+// repository it wraps.
+func LabelsOf(r Repository) map[string]string {
+	if labeled, ok := r.(interface{ GetLabels() map[string]string }); ok {
+		if labels := labeled.GetLabels(); len(labels) > 0 {
+			return labels
+		}
+	}
+	switch wrapper := r.(type) {
+	case *HookRepository:
+		return LabelsOf(wrapper.Repository)
+	case *HistoryRepository:
+		return LabelsOf(wrapper.Repository)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package source
+
+import "testing"
+
+// TestLabelsOf tests that labels are read from repositories and the wrappers around them
+func TestLabelsOf(t *testing.T) {
+	file := &FileRepository{Name: "test", Labels: Labels{"team": "payments"}}
+
+	tests := []struct {
+		name string
+		repo Repository
+		want string
+	}{
+		{"repository", file, "payments"},
+		{"unlabeled", &FileRepository{Name: "test"}, ""},
+		{"hook wrapper", &HookRepository{Repository: file}, "payments"},
+		{"history wrapper", &HistoryRepository{Repository: &HookRepository{Repository: file}}, "payments"},
+		{"wrapper labels", &HookRepository{Repository: file, Labels: Labels{"team": "search"}}, "search"},
+		{"failover", &FailoverRepository{Name: "test", Primary: file, Labels: Labels{"team": "search"}}, "search"},
+	}
+	for _, tt := range tests {
+		if got := LabelsOf(tt.repo)["team"]; got != tt.want {
+			t.Errorf("%s: Expected team %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package source
+
+import (
+	"math/big"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// float64Digits is the number of significant decimal digits a float64 is
+// guaranteed to round-trip.
+const float64Digits = 15
+
+// preserveBigNumbers retags the numeric scalars under node that would lose
+// precision when decoded into a Go number as strings, so they decode to their
+// literal text.
+func preserveBigNumbers(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		switch node.ShortTag() {
+		case "!!int":
+			if !fitsInt(node.Value) {
+				node.Tag = "!!str"
+			}
+		case "!!float":
+			if significantDigits(node.Value) > float64Digits {
+				node.Tag = "!!str"
+			}
+		}
+		return
+	}
+	for _, child := range node.Content {
+		preserveBigNumbers(child)
+	}
+}
+
+// fitsInt reports whether the YAML integer literal fits an int64 or uint64,
+// the types yaml.v3 decodes integers into before falling back to float64.
+func fitsInt(value string) bool {
+	n, ok := new(big.Int).SetString(value, 0)
+	return !ok || n.IsInt64() || n.IsUint64()
+}
+
+// significantDigits counts the significant digits in the mantissa of a YAML
+// float literal.
+func significantDigits(value string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(value), "e")
+	mantissa = strings.TrimLeft(mantissa, "+-")
+	mantissa = strings.ReplaceAll(mantissa, "_", "")
+	mantissa = strings.Replace(mantissa, ".", "", 1)
+	mantissa = strings.TrimRight(strings.TrimLeft(mantissa, "0"), "0")
+	return len(mantissa)
+}
@@ -0,0 +1,76 @@
+package source
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// object is the content of one fetched bucket object.
+type object struct {
+	location string // e.g. "s3://bucket/flags.yaml", used in error messages
+	content  []byte
+}
+
+// objectNames returns the objects a bucket repository reads: names when set,
+// otherwise the single name.
+func objectNames(name string, names []string) []string {
+	if len(names) > 0 {
+		return names
+	}
+	return []string{name}
+}
+
+// decodeObjects decodes the objects of a bucket repository into one data map.
+// A single object is decoded as-is and its content is the raw data. Several
+// objects are merged in order, later objects overriding the top-level keys
+// of earlier ones, and the raw data is the merged document. SOPS-encrypted
+// objects cannot be merged, since the merged document would expose the
+// decrypted values.
+func (o DecodeOptions) decodeObjects(name string, objects []object) (map[string]interface{}, []byte, error) {
+	if len(objects) == 1 {
+		return o.decode(name, objects[0].location, objects[0].content)
+	}
+
+	// Select the root of, transform and validate the merged data rather
+	// than each object
+	merge := o
+	o.RootKey = ""
+	o.MapTransforms = nil
+	o.Validator = nil
+	o.OnEmpty = EmptyAllow
+
+	merged := make(map[string]interface{})
+	for _, obj := range objects {
+		if o.DecryptSops && hasSopsMetadata(obj.content) {
+			return nil, nil, fmt.Errorf("repository %q: cannot merge SOPS-encrypted object %s", name, obj.location)
+		}
+		data, _, err := o.decode(name, obj.location, obj.content)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range data {
+			merged[key] = value
+		}
+	}
+	merged, err := merge.selectRoot(name, "merged objects", merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := merge.transform(name, "merged objects", merged); err != nil {
+		return nil, nil, err
+	}
+	if err := merge.checkEmpty(name, "merged objects", merged); err != nil {
+		return nil, nil, err
+	}
+	if merge.Validator != nil {
+		if err := merge.Validator(merged); err != nil {
+			return nil, nil, fmt.Errorf("repository %q: invalid config in merged objects: %w", name, err)
+		}
+	}
+	raw, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository %q: error encoding merged objects: %w", name, err)
+	}
+	return merged, raw, nil
+}
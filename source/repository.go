@@ -1,10 +1,37 @@
 package source
 
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Repository types reported by Repository.Type.
+const (
+	TypeFile       = "file"
+	TypeWeb        = "web"
+	TypeGit        = "git"
+	TypeGitHub     = "github"
+	TypeSFTP       = "sftp"
+	TypeAwsS3      = "s3"
+	TypeGcpStorage = "gcs"
+	TypeConfigMap  = "configmap"
+	TypeDynamoDB   = "dynamodb"
+	TypeSQL        = "sql"
+	TypeFailover   = "failover"
+)
+
 // Repository is an interface that defines the contract for a configuration data repository.
 // Any type implementing this interface must provide methods to retrieve the configuration data
 // and to refresh the data when required.
 type Repository interface {
 	GetName() string
+
+	// Type returns the kind of backing source, one of the Type* constants for
+	// the repositories in this package.
+	Type() string
+
 	// GetData returns the configuration data as a map of configuration names to their respective models.
 	GetData(string) (interface{}, bool)
 
@@ -18,3 +45,77 @@ type Repository interface {
 	// The caller of this method should handle the error appropriately.
 	Refresh() error
 }
+
+// DataDumper is implemented by repositories that can return their whole
+// decoded configuration map. All repositories in this package implement it;
+// use AllData to read the map of any repository.
+type DataDumper interface {
+	// GetAllData returns a deep copy of the decoded configuration map, or nil
+	// if the repository has not been loaded yet.
+	GetAllData() map[string]interface{}
+}
+
+// AllData returns a deep copy of the decoded configuration map of r: its
+// GetAllData if it implements DataDumper, otherwise its raw data decoded as
+// YAML. It returns nil if r has not been loaded yet or its raw data cannot be
+// decoded.
+func AllData(r Repository) map[string]interface{} {
+	if dumper, ok := r.(DataDumper); ok {
+		return dumper.GetAllData()
+	}
+	raw := r.GetRawData()
+	if len(raw) == 0 {
+		return nil
+	}
+	data, _, err := DecodeOptions{OnEmpty: EmptyAllow}.decode(r.GetName(), "raw data", raw)
+	if err != nil {
+		logrus.WithError(err).WithField("repository", r.GetName()).Debug("error decoding raw data")
+		return nil
+	}
+	return data
+}
+
+// KeyLister is implemented by repositories that can list their top-level
+// configuration names without copying the data, as all repositories in this
+// package do. Use Keys to list the names of any repository.
+type KeyLister interface {
+	// Keys returns the top-level configuration names in no particular
+	// order, or nil if the repository has not been loaded yet.
+	Keys() []string
+}
+
+// Keys returns the top-level configuration names of r in no particular
+// order: its Keys if it implements KeyLister, otherwise the keys of AllData.
+func Keys(r Repository) []string {
+	if lister, ok := r.(KeyLister); ok {
+		return lister.Keys()
+	}
+	return mapKeys(AllData(r))
+}
+
+// mapKeys returns the keys of data in no particular order, or nil if data is
+// nil.
+func mapKeys(data map[string]interface{}) []string {
+	if data == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// SafeRefresh calls r.Refresh and converts a panic into an error, so a
+// decoder bug or a nil pointer in a custom repository fails the refresh
+// instead of taking down the refresh goroutine and the process with it. The
+// stack of the panic is logged at error level.
+func SafeRefresh(r Repository) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logrus.WithField("repository", r.GetName()).Errorf("panic refreshing repository: %v\n%s", p, debug.Stack())
+			err = fmt.Errorf("repository %q: refresh panicked: %v", r.GetName(), p)
+		}
+	}()
+	return r.Refresh()
+}
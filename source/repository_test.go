@@ -0,0 +1,104 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestRepositoryTypes tests that each repository reports its type
+func TestRepositoryTypes(t *testing.T) {
+	tests := []struct {
+		repo Repository
+		want string
+	}{
+		{&FileRepository{}, TypeFile},
+		{&WebRepository{}, TypeWeb},
+		{&GitRepository{}, TypeGit},
+		{&AwsS3Repository{}, TypeAwsS3},
+		{&GcpStorageRepository{}, TypeGcpStorage},
+		{&ConfigMapRepository{}, TypeConfigMap},
+		{&SFTPRepository{}, TypeSFTP},
+		{&FailoverRepository{}, TypeFailover},
+	}
+	for _, tt := range tests {
+		if got := tt.repo.Type(); got != tt.want {
+			t.Errorf("%T: Expected type '%s', got '%s'", tt.repo, tt.want, got)
+		}
+	}
+}
+
+// panickingRepository is a repository whose Refresh panics
+type panickingRepository struct {
+	FileRepository
+}
+
+func (p *panickingRepository) Refresh() error {
+	var data map[string]interface{}
+	data["key"] = "value"
+	return nil
+}
+
+// TestSafeRefresh tests that SafeRefresh turns a panic into an error
+func TestSafeRefresh(t *testing.T) {
+	repo := &panickingRepository{FileRepository{Name: "broken"}}
+	err := SafeRefresh(repo)
+	if err == nil {
+		t.Fatal("Expected an error from a panicking refresh")
+	}
+	if !strings.Contains(err.Error(), `repository "broken": refresh panicked`) {
+		t.Errorf("Expected the error to name the repository and the panic, got '%v'", err)
+	}
+
+	if err := SafeRefresh(&FileRepository{Name: "missing", Path: "testdata/does-not-exist.yaml"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the refresh error to be returned unchanged, got '%v'", err)
+	}
+}
+
+// rawOnlyRepository is a repository that does not implement DataDumper
+type rawOnlyRepository struct {
+	rawData []byte
+}
+
+func (r *rawOnlyRepository) GetName() string                    { return "raw-only" }
+func (r *rawOnlyRepository) Type() string                       { return "custom" }
+func (r *rawOnlyRepository) GetData(string) (interface{}, bool) { return nil, false }
+func (r *rawOnlyRepository) GetRawData() []byte                 { return r.rawData }
+func (r *rawOnlyRepository) Refresh() error                     { return nil }
+
+// TestAllData tests that AllData and Keys decode the raw data of repositories without GetAllData or Keys
+func TestAllData(t *testing.T) {
+	repo := &rawOnlyRepository{rawData: []byte("a: 1\n---\nb: 2\n")}
+	data := AllData(repo)
+	if data["a"] != 1 || data["b"] != 2 {
+		t.Errorf("Expected both documents decoded, got %v", data)
+	}
+	if data := AllData(&rawOnlyRepository{}); data != nil {
+		t.Errorf("Expected nil before the repository loads, got %v", data)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("key: value\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	file := &FileRepository{Name: "file", Path: path}
+	if err := file.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	hook := &HookRepository{Repository: file}
+	if got := AllData(hook); got["key"] != "value" {
+		t.Errorf("Expected the wrapped repository's data, got %v", got)
+	}
+	if got := Keys(hook); !reflect.DeepEqual(got, []string{"key"}) {
+		t.Errorf("Expected the wrapped repository's keys, got %v", got)
+	}
+	keys := Keys(repo)
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("Expected the keys of the decoded raw data, got %v", keys)
+	}
+}
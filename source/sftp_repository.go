@@ -0,0 +1,213 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultSFTPTimeout bounds connecting and authenticating to the SFTP server
+// when Timeout is not set.
+const defaultSFTPTimeout = 30 * time.Second
+
+// SFTPRepository is a struct that implements the Repository interface for
+// handling configuration data stored in a YAML file on an SFTP server. Each
+// refresh opens a new SSH connection, reads the file and closes it again,
+// so no connection is held between refreshes.
+type SFTPRepository struct {
+	sync.RWMutex                           // RWMutex to synchronize access to data during refresh
+	DecodeOptions                          // Options controlling how raw data is decoded
+	DiskCache                              // Persists the last good data across restarts when CacheDir is set
+	Labels                                 // Optional labels reported in a config server's status, e.g. team or environment
+	Name            string                 // Name of the configuration source
+	Host            string                 // Address of the SFTP server, "host" or "host:port", port defaults to 22
+	User            string                 // User to log in as
+	Password        string                 // Password for password authentication
+	PrivateKey      []byte                 // PEM-encoded private key for key-based authentication, tried before Password
+	Passphrase      []byte                 // Passphrase of PrivateKey if it is encrypted
+	HostKeyCallback ssh.HostKeyCallback    // Verifies the server's host key, e.g. from knownhosts.New; required
+	Path            string                 // Path of the YAML file on the server
+	Timeout         time.Duration          // Timeout for connecting and authenticating, defaults to 30 seconds
+	data            map[string]interface{} // Map to store the configuration data
+	rawData         []byte                 // Raw data of the YAML configuration file
+}
+
+// GetName returns the name of the configuration source.
+func (s *SFTPRepository) GetName() string {
+	return s.Name
+}
+
+// Type returns the repository type.
+func (s *SFTPRepository) Type() string {
+	return TypeSFTP
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (s *SFTPRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	s.RLock()
+	defer s.RUnlock()
+	config, isPresent = s.data[configName]
+	return config, isPresent
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (s *SFTPRepository) GetAllData() map[string]interface{} {
+	s.RLock()
+	defer s.RUnlock()
+	return deepcopy.Map(s.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (s *SFTPRepository) Keys() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return mapKeys(s.data)
+}
+
+// GetRawData returns the raw data of the YAML configuration file.
+func (s *SFTPRepository) GetRawData() []byte {
+	s.RLock()
+	defer s.RUnlock()
+	return s.rawData
+}
+
+// clientConfig returns the SSH client configuration for the repository's
+// credentials.
+func (s *SFTPRepository) clientConfig() (*ssh.ClientConfig, error) {
+	if s.HostKeyCallback == nil {
+		return nil, fmt.Errorf("repository %q: HostKeyCallback is required", s.Name)
+	}
+	var auth []ssh.AuthMethod
+	if len(s.PrivateKey) > 0 {
+		var signer ssh.Signer
+		var err error
+		if len(s.Passphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(s.PrivateKey, s.Passphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(s.PrivateKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("repository %q: error parsing private key: %w", s.Name, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.Password != "" {
+		auth = append(auth, ssh.Password(s.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("repository %q: PrivateKey or Password is required", s.Name)
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultSFTPTimeout
+	}
+	return &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            auth,
+		HostKeyCallback: s.HostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// address returns Host with the default SSH port added if it has none.
+func (s *SFTPRepository) address() string {
+	if _, _, err := net.SplitHostPort(s.Host); err == nil {
+		return s.Host
+	}
+	return net.JoinHostPort(s.Host, "22")
+}
+
+// Refresh connects to the SFTP server, reads the YAML file and unmarshal it
+// into the data map.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
+func (s *SFTPRepository) Refresh() error {
+	return s.cached(s, s.DecodeOptions, "sftp://"+s.Host+"/"+strings.TrimPrefix(s.Path, "/"), s.refresh, func(data map[string]interface{}, rawData []byte) {
+		s.Lock()
+		s.data, s.rawData = data, rawData
+		s.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (s *SFTPRepository) refresh() error {
+	location := "sftp://" + s.Host + "/" + strings.TrimPrefix(s.Path, "/")
+
+	config, err := s.clientConfig()
+	if err != nil {
+		return err
+	}
+
+	// Network I/O outside lock for better performance
+	data, err := s.readFile(config)
+	if err != nil {
+		logrus.Debug("error reading file")
+		return err
+	}
+
+	// Unmarshal to temp variable outside lock to prevent data corruption on error
+	tempData, data, err := s.decode(s.Name, location, data)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	// Only lock for atomic data swap
+	s.Lock()
+	s.data = tempData
+	s.rawData = data
+	s.Unlock()
+
+	return nil
+}
+
+// readFile reads Path over a new SSH connection.
+func (s *SFTPRepository) readFile(config *ssh.ClientConfig) ([]byte, error) {
+	conn, err := ssh.Dial("tcp", s.address(), config)
+	if err != nil {
+		return nil, withKind(sshKind(err), fmt.Errorf("repository %q: error connecting to %s: %w", s.Name, s.Host, err))
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return nil, withKind(ErrUnreachable, fmt.Errorf("repository %q: error starting sftp session: %w", s.Name, err))
+	}
+	defer client.Close()
+
+	file, err := client.Open(s.Path)
+	if err != nil {
+		return nil, withKind(fileKind(err), fmt.Errorf("repository %q: error opening %s: %w", s.Name, s.Path, err))
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, withKind(ErrUnreachable, fmt.Errorf("repository %q: error reading %s: %w", s.Name, s.Path, err))
+	}
+	return data, nil
+}
+
+// sshKind maps an error connecting to an SSH server to an error kind, or
+// nil if it has none.
+func sshKind(err error) error {
+	var keyErr *knownhosts.KeyError
+	switch {
+	case errors.As(err, &keyErr):
+		// Host key mismatch: the server is not the one we trust
+		return ErrPermission
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return ErrPermission
+	default:
+		return networkKind(err)
+	}
+}
@@ -0,0 +1,200 @@
+package source
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newSFTPServer starts an in-process SFTP server accepting the given password
+// or client key for user "config". It returns the server address and host key.
+func newSFTPServer(t *testing.T, password string, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	if err != nil {
+		t.Fatalf("Failed to create host signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, given []byte) (*ssh.Permissions, error) {
+			if conn.User() == "config" && password != "" && string(given) == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "config" && clientKey != nil && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+	return listener.Addr().String(), hostSigner.PublicKey()
+}
+
+// serveSFTP serves the sftp subsystem on an accepted SSH connection.
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for request := range requests {
+				// The payload is the length-prefixed subsystem name
+				ok := request.Type == "subsystem" && string(request.Payload[4:]) == "sftp"
+				request.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(channel, sftp.ReadOnly())
+					if err == nil {
+						server.Serve()
+						server.Close()
+					}
+				}
+			}
+		}()
+	}
+}
+
+// newClientKey generates a client key pair, returning the PEM-encoded private key.
+func newClientKey(t *testing.T) ([]byte, ssh.PublicKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal client key: %v", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatalf("Failed to convert client key: %v", err)
+	}
+	return pem.EncodeToMemory(block), sshPublic
+}
+
+// TestSFTPRepositoryRefresh tests reading the file with password and key-based auth
+func TestSFTPRepositoryRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("timeout: 30\nregion: eu\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	privateKey, publicKey := newClientKey(t)
+	addr, hostKey := newSFTPServer(t, "hunter2", publicKey)
+
+	tests := []struct {
+		name string
+		repo *SFTPRepository
+	}{
+		{"password", &SFTPRepository{Password: "hunter2"}},
+		{"private key", &SFTPRepository{PrivateKey: privateKey}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			repo.Name = "legacy"
+			repo.Host = addr
+			repo.User = "config"
+			repo.Path = path
+			repo.HostKeyCallback = ssh.FixedHostKey(hostKey)
+			if err := repo.Refresh(); err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if v, _ := repo.GetData("timeout"); v != 30 {
+				t.Errorf("Expected timeout 30, got %v", v)
+			}
+			if v, _ := repo.GetData("region"); v != "eu" {
+				t.Errorf("Expected region 'eu', got %v", v)
+			}
+			if string(repo.GetRawData()) != "timeout: 30\nregion: eu\n" {
+				t.Errorf("Expected the file as raw data, got '%s'", repo.GetRawData())
+			}
+		})
+	}
+}
+
+// TestSFTPRepositoryErrors tests the error kinds of failed refreshes
+func TestSFTPRepositoryErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("timeout: 30\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	addr, hostKey := newSFTPServer(t, "hunter2", nil)
+	_, otherHostKey := newClientKey(t)
+
+	tests := []struct {
+		name string
+		repo *SFTPRepository
+		kind error
+	}{
+		{"wrong password", &SFTPRepository{Password: "wrong", Path: path}, ErrPermission},
+		{"missing file", &SFTPRepository{Password: "hunter2", Path: path + ".missing"}, ErrNotFound},
+		{"unexpected host key", &SFTPRepository{Password: "hunter2", Path: path, HostKeyCallback: ssh.FixedHostKey(otherHostKey)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			repo.Name = "legacy"
+			repo.Host = addr
+			repo.User = "config"
+			if repo.HostKeyCallback == nil {
+				repo.HostKeyCallback = ssh.FixedHostKey(hostKey)
+			}
+			err := repo.Refresh()
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("Expected %v, got '%v'", tt.kind, err)
+			}
+			if repo.GetAllData() != nil {
+				t.Error("Expected no data after a failed refresh")
+			}
+		})
+	}
+
+	repo := &SFTPRepository{Name: "legacy", Host: addr, User: "config", Password: "hunter2", Path: path}
+	if err := repo.Refresh(); err == nil || !strings.Contains(err.Error(), "HostKeyCallback is required") {
+		t.Errorf("Expected HostKeyCallback to be required, got '%v'", err)
+	}
+}
@@ -0,0 +1,35 @@
+package source
+
+import (
+	"fmt"
+
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// decryptSops returns the plaintext of a SOPS-encrypted document when
+// DecryptSops is set and data carries SOPS metadata; otherwise data is
+// returned unchanged. The returned bool reports whether data was decrypted.
+func (o DecodeOptions) decryptSops(name, location string, data []byte) ([]byte, bool, error) {
+	if !o.DecryptSops || !hasSopsMetadata(data) {
+		return data, false, nil
+	}
+	plaintext, err := decrypt.DataWithFormat(data, formats.Yaml)
+	if err != nil {
+		return nil, false, fmt.Errorf("repository %q: error decrypting %s: %w", name, location, err)
+	}
+	return plaintext, true, nil
+}
+
+// hasSopsMetadata reports whether data is a YAML document with a top-level
+// "sops" mapping, which SOPS adds to every file it encrypts.
+func hasSopsMetadata(data []byte) bool {
+	var doc struct {
+		Sops yaml.Node `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.Sops.Kind == yaml.MappingNode
+}
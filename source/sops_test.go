@@ -0,0 +1,74 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+// sopsFixture is a file encrypted with the test-only age key in testdata/sops/age.key
+const sopsFixture = "testdata/sops/secrets.enc.yaml"
+
+// TestFileRepositoryDecryptSops tests that SOPS files are decrypted while raw data stays encrypted
+func TestFileRepositoryDecryptSops(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/sops/age.key")
+	repo := &FileRepository{Name: "secrets", Path: sopsFixture, DecodeOptions: DecodeOptions{DecryptSops: true}}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("api_token"); val != "s3cr3t" {
+		t.Errorf("Expected decrypted api_token, got %v", val)
+	}
+	if val, _ := repo.GetData("replicas"); val != 3 {
+		t.Errorf("Expected decrypted replicas 3, got %v", val)
+	}
+	val, _ := repo.GetData("database")
+	if db, ok := val.(map[string]interface{}); !ok || db["password"] != "hunter2" {
+		t.Errorf("Expected decrypted database password, got %v", val)
+	}
+	if _, ok := repo.GetData("sops"); ok {
+		t.Error("Expected SOPS metadata to be stripped from the data")
+	}
+
+	raw := string(repo.GetRawData())
+	if strings.Contains(raw, "hunter2") || !strings.Contains(raw, "ENC[AES256_GCM") {
+		t.Errorf("Expected raw data to stay encrypted, got: %s", raw)
+	}
+}
+
+// TestFileRepositorySopsDisabled tests that encrypted files are decoded as-is without DecryptSops
+func TestFileRepositorySopsDisabled(t *testing.T) {
+	repo := &FileRepository{Name: "secrets", Path: sopsFixture}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("api_token"); !strings.HasPrefix(val.(string), "ENC[") {
+		t.Errorf("Expected encrypted api_token, got %v", val)
+	}
+}
+
+// TestFileRepositorySopsMissingKey tests that decryption fails without the key
+func TestFileRepositorySopsMissingKey(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/sops/missing.key")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := &FileRepository{Name: "secrets", Path: sopsFixture, DecodeOptions: DecodeOptions{DecryptSops: true}}
+
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error without a decryption key")
+	}
+	if !strings.Contains(err.Error(), "error decrypting") {
+		t.Errorf("Expected decryption error, got: %v", err)
+	}
+}
+
+// TestDecryptSopsPlaintext tests that plaintext documents decode normally with DecryptSops set
+func TestDecryptSopsPlaintext(t *testing.T) {
+	out, _, err := DecodeOptions{DecryptSops: true}.decode("plain", "inline", []byte("key: value\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if out["key"] != "value" {
+		t.Errorf("Expected 'value', got '%v'", out["key"])
+	}
+}
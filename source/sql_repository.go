@@ -0,0 +1,179 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
+	"gopkg.in/yaml.v3"
+)
+
+// SQLRepository is a struct that implements the Repository interface for
+// handling configuration data stored in a SQL database.
+//
+// The shape of the configuration is taken from the columns Query returns:
+//
+//   - One column: a single row holding a YAML (or JSON) document, decoded
+//     like a file, e.g. SELECT body FROM config WHERE app = 'my-app'.
+//   - Two columns: one row per top-level key, with the value parsed as YAML
+//     so numbers, booleans and nested documents keep their types, e.g.
+//     SELECT name, value FROM settings. GetRawData then returns the rows as
+//     a YAML document.
+type SQLRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	DecodeOptions                        // Options controlling how raw data is decoded
+	DiskCache                            // Persists the last good data across restarts when CacheDir is set
+	Labels                               // Optional labels reported in a config server's status, e.g. team or environment
+	Name          string                 // Name of the configuration source
+	DB            *sql.DB                // Database to query; its driver and pool are managed by the caller
+	Query         string                 // Query returning a document column or key/value rows
+	Args          []interface{}          // Optional query arguments
+	data          map[string]interface{} // Map to store the configuration data
+	rawData       []byte                 // Raw data of the YAML configuration
+}
+
+// Refresh runs the query, unmarshal its result into the data map.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
+func (s *SQLRepository) Refresh() error {
+	return s.cached(s, s.DecodeOptions, s.Query, s.refresh, func(data map[string]interface{}, rawData []byte) {
+		s.Lock()
+		s.data, s.rawData = data, rawData
+		s.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (s *SQLRepository) refresh() error {
+	// Database I/O outside lock for better performance
+	rows, err := s.DB.QueryContext(context.Background(), s.Query, s.Args...)
+	if err != nil {
+		return withKind(networkKind(err), fmt.Errorf("repository %q: error querying config: %w", s.Name, err))
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("repository %q: %w", s.Name, err)
+	}
+
+	// Unmarshal to temp variable outside lock to prevent data corruption on error
+	var tempData map[string]interface{}
+	var fileContent []byte
+	switch len(columns) {
+	case 1:
+		tempData, fileContent, err = s.decodeDocument(rows)
+	case 2:
+		tempData, fileContent, err = s.decodeRows(rows)
+	default:
+		err = fmt.Errorf("repository %q: query must return 1 or 2 columns, got %d", s.Name, len(columns))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only lock for atomic data swap
+	s.Lock()
+	s.data = tempData
+	s.rawData = fileContent
+	s.Unlock()
+
+	return nil
+}
+
+// decodeDocument decodes the single document row returned by the query.
+func (s *SQLRepository) decodeDocument(rows *sql.Rows) (map[string]interface{}, []byte, error) {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("repository %q: %w", s.Name, err)
+		}
+		return nil, nil, withKind(ErrNotFound, fmt.Errorf("repository %q: query returned no rows", s.Name))
+	}
+	var document []byte
+	if err := rows.Scan(&document); err != nil {
+		return nil, nil, fmt.Errorf("repository %q: %w", s.Name, err)
+	}
+	if rows.Next() {
+		return nil, nil, fmt.Errorf("repository %q: query returned more than one row", s.Name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("repository %q: %w", s.Name, err)
+	}
+	return s.decode(s.Name, "query result", document)
+}
+
+// decodeRows decodes the key/value rows returned by the query.
+func (s *SQLRepository) decodeRows(rows *sql.Rows) (map[string]interface{}, []byte, error) {
+	values := make(map[string]interface{})
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, nil, fmt.Errorf("repository %q: %w", s.Name, err)
+		}
+		if _, ok := values[key]; ok {
+			return nil, nil, withKind(ErrParse, fmt.Errorf("repository %q: duplicate key %q", s.Name, key))
+		}
+		var parsed interface{}
+		if value.Valid {
+			if err := yaml.Unmarshal([]byte(value.String), &parsed); err != nil {
+				return nil, nil, withKind(ErrParse, fmt.Errorf("repository %q: error decoding key %q: %w", s.Name, key, err))
+			}
+		}
+		values[key] = parsed
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("repository %q: %w", s.Name, err)
+	}
+
+	// The document is generated, so it is neither a template nor
+	// SOPS-encrypted
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository %q: error encoding query result: %w", s.Name, err)
+	}
+	options := s.DecodeOptions
+	options.Template = false
+	options.DecryptSops = false
+	return options.decode(s.Name, "query result", data)
+}
+
+// GetName returns the name of the configuration source.
+func (s *SQLRepository) GetName() string {
+	return s.Name
+}
+
+// Type returns the repository type.
+func (s *SQLRepository) Type() string {
+	return TypeSQL
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (s *SQLRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	s.RLock()
+	defer s.RUnlock()
+	config, isPresent = s.data[configName]
+	return config, isPresent
+}
+
+// GetAllData returns a deep copy of the decoded configuration map.
+func (s *SQLRepository) GetAllData() map[string]interface{} {
+	s.RLock()
+	defer s.RUnlock()
+	return deepcopy.Map(s.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (s *SQLRepository) Keys() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return mapKeys(s.data)
+}
+
+// GetRawData returns the raw data of the YAML configuration.
+func (s *SQLRepository) GetRawData() []byte {
+	s.RLock()
+	defer s.RUnlock()
+	return s.rawData
+}
@@ -0,0 +1,118 @@
+package source
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestSQLRepositoryDocument tests reading a single YAML document column
+func TestSQLRepositoryDocument(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT body FROM config WHERE app = ?")).
+		WithArgs("my-app").
+		WillReturnRows(sqlmock.NewRows([]string{"body"}).AddRow("name: app\nport: 8080\n"))
+
+	repo := &SQLRepository{
+		Name:  "app",
+		DB:    db,
+		Query: "SELECT body FROM config WHERE app = ?",
+		Args:  []interface{}{"my-app"},
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("port"); val != 8080 {
+		t.Errorf("Expected port 8080, got %v", val)
+	}
+	if string(repo.GetRawData()) != "name: app\nport: 8080\n" {
+		t.Errorf("Expected raw data to match, got: %s", string(repo.GetRawData()))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+// TestSQLRepositoryKeyValueRows tests building the config from key/value rows
+func TestSQLRepositoryKeyValueRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT name, value FROM settings").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).
+			AddRow("timeout", "30").
+			AddRow("dark_mode", "true").
+			AddRow("routes", "[/api, /admin]").
+			AddRow("unset", nil))
+
+	repo := &SQLRepository{Name: "settings", DB: db, Query: "SELECT name, value FROM settings"}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("timeout"); val != 30 {
+		t.Errorf("Expected timeout 30, got %v", val)
+	}
+	if val, _ := repo.GetData("dark_mode"); val != true {
+		t.Errorf("Expected dark_mode true, got %v", val)
+	}
+	if val, _ := repo.GetData("routes"); len(val.([]interface{})) != 2 {
+		t.Errorf("Expected two routes, got %v", val)
+	}
+	if val, ok := repo.GetData("unset"); !ok || val != nil {
+		t.Errorf("Expected NULL to be a present nil value, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); !strings.Contains(raw, "timeout: 30\n") {
+		t.Errorf("Expected raw data to be the rows as YAML, got %q", raw)
+	}
+}
+
+// TestSQLRepositoryErrors tests that unexpected result shapes fail the refresh and keep the old data
+func TestSQLRepositoryErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	repo := &SQLRepository{Name: "settings", DB: db, Query: "SELECT"}
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).AddRow("key", "value"))
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want string
+		kind error
+	}{
+		{"no rows", sqlmock.NewRows([]string{"body"}), "no rows", ErrNotFound},
+		{"several documents", sqlmock.NewRows([]string{"body"}).AddRow("a: 1").AddRow("b: 2"), "more than one row", nil},
+		{"duplicate key", sqlmock.NewRows([]string{"name", "value"}).AddRow("a", "1").AddRow("a", "2"), "duplicate key", ErrParse},
+		{"too many columns", sqlmock.NewRows([]string{"a", "b", "c"}), "1 or 2 columns", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT").WillReturnRows(tt.rows)
+			err := repo.Refresh()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("Expected %v, got: %v", tt.kind, err)
+			}
+			if val, _ := repo.GetData("key"); val != "value" {
+				t.Errorf("Expected old data to survive, got %v", val)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// templateData is the data context templates are executed with.
+type templateData struct {
+	Env map[string]string // Environment variables, e.g. {{ .Env.DB_HOST }}
+}
+
+// templateFuncs are the functions available to templates in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	// env returns the named environment variable, or "" if it is unset
+	"env": os.Getenv,
+	// default returns value, or def if value is empty: {{ env "PORT" | default "8080" }}
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+	// required fails the render if value is empty: {{ env "DB_HOST" | required "DB_HOST" }}
+	"required": func(name, value string) (string, error) {
+		if value == "" {
+			return "", fmt.Errorf("%s is required", name)
+		}
+		return value, nil
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// render executes data as a text/template when Template is set. Referencing
+// a missing key in .Env is an error.
+func (o DecodeOptions) render(name, location string, data []byte) ([]byte, error) {
+	if !o.Template {
+		return data, nil
+	}
+	tmpl, err := template.New(location).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, withKind(ErrParse, fmt.Errorf("repository %q: error parsing template %s: %w", name, location, err))
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData{Env: environ()}); err != nil {
+		return nil, withKind(ErrParse, fmt.Errorf("repository %q: error rendering template %s: %w", name, location, err))
+	}
+	return buf.Bytes(), nil
+}
+
+// environ returns the environment as a map.
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
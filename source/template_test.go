@@ -0,0 +1,71 @@
+package source
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestFileRepositoryTemplate tests that templates are rendered with env vars before decoding
+func TestFileRepositoryTemplate(t *testing.T) {
+	t.Setenv("TEMPLATE_DB_HOST", "db.internal")
+	t.Setenv("TEMPLATE_REGION", "EU-West-1")
+	content := `database_url: "postgres://{{ .Env.TEMPLATE_DB_HOST }}:{{ env "TEMPLATE_DB_PORT" | default "5432" }}/app"
+region: {{ env "TEMPLATE_REGION" | lower }}
+`
+	path := writeConfig(t, "config.yaml", content)
+	repo := &FileRepository{Name: "test", Path: path, DecodeOptions: DecodeOptions{Template: true}}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("database_url"); val != "postgres://db.internal:5432/app" {
+		t.Errorf("Expected rendered database_url, got %v", val)
+	}
+	if val, _ := repo.GetData("region"); val != "eu-west-1" {
+		t.Errorf("Expected rendered region, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); strings.Contains(raw, "{{") {
+		t.Errorf("Expected raw data to be the rendered document, got: %s", raw)
+	}
+}
+
+// TestFileRepositoryTemplateDisabled tests that template syntax is left alone without Template
+func TestFileRepositoryTemplateDisabled(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "greeting: \"{{ .Name }}\"\n")
+	repo := &FileRepository{Name: "test", Path: path}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("greeting"); val != "{{ .Name }}" {
+		t.Errorf("Expected literal template text, got %v", val)
+	}
+}
+
+// TestFileRepositoryTemplateErrorKeepsData tests that a template error keeps the previous data
+func TestFileRepositoryTemplateErrorKeepsData(t *testing.T) {
+	t.Setenv("TEMPLATE_DB_HOST", "db.internal")
+	path := writeConfig(t, "config.yaml", "host: {{ .Env.TEMPLATE_DB_HOST }}\n")
+	repo := &FileRepository{Name: "test", Path: path, DecodeOptions: DecodeOptions{Template: true}}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, content := range []string{
+		"host: {{ .Env.TEMPLATE_MISSING_VAR }}\n",
+		"host: {{ env \"TEMPLATE_MISSING_VAR\" | required \"TEMPLATE_MISSING_VAR\" }}\n",
+		"host: {{ .Env.TEMPLATE_DB_HOST\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		err := repo.Refresh()
+		if err == nil || !strings.Contains(err.Error(), "template") {
+			t.Errorf("Expected template error for %q, got: %v", content, err)
+		}
+		if val, _ := repo.GetData("host"); val != "db.internal" {
+			t.Errorf("Expected previous data to be kept, got %v", val)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Watcher is implemented by repositories that can block until their source
+// reports a change, allowing clients to refresh immediately instead of waiting
+// for the next refresh tick.
+type Watcher interface {
+	// WaitForChange blocks until the source content differs from the data
+	// currently held by the repository, the source gives up waiting, or ctx is
+	// done. It reports whether a change was detected.
+	WaitForChange(ctx context.Context) (bool, error)
+}
+
+// ContentHash returns the hex-encoded SHA-256 hash of raw configuration data.
+// The server and watching repositories use it to detect content changes.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,24 +1,82 @@
 package source
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/sardine-ai/go-remote-config/internal/deepcopy"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 )
 
 // WebRepository is a struct that implements the Repository interface for
 // handling configuration data fetched from a remote HTTP endpoint (web URL).
 type WebRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name         string                 // Name of the configuration source
-	data         map[string]interface{} // Map to store the configuration data
-	URL          *url.URL               // URL representing the remote HTTP endpoint (web URL)
-	rawData      []byte                 // Raw data of the YAML configuration file
-	APIKey       string                 // Optional API key for X-API-Key header authentication
+	sync.RWMutex                           // RWMutex to synchronize access to data during refresh
+	DecodeOptions                          // Options controlling how raw data is decoded
+	DiskCache                              // Persists the last good data across restarts when CacheDir is set
+	Labels                                 // Optional labels reported in a config server's status, e.g. team or environment
+	Name            string                 // Name of the configuration source
+	data            map[string]interface{} // Map to store the configuration data
+	URL             *url.URL               // URL representing the remote HTTP endpoint (web URL)
+	rawData         []byte                 // Raw data of the YAML configuration file
+	etag            string                 // ETag of the response rawData was decoded from, sent as If-None-Match
+	APIKey          string                 // Optional API key for X-API-Key header authentication
+	WatchURL        *url.URL               // Optional long-poll endpoint (a config server's /watch/{repo}) used by WaitForChange
+	ClientCert      *tls.Certificate       // Optional client certificate presented for mutual TLS
+	RootCAs         *x509.CertPool         // Optional CAs trusted for the server certificate instead of the system pool
+	RejectRedirects bool                   // Fail the refresh on a 3xx response (e.g. to a login page) and keep the current data instead of following it
+	MaxRedirects    int                    // Redirects followed before failing unless RejectRedirects is set; defaults to 10
+	clientOnce      sync.Once              // Ensures client is initialized only once
+	client          *http.Client           // HTTP client, see httpClient
+}
+
+// defaultMaxRedirects is the number of redirects followed when MaxRedirects
+// is not set, the same as http.Client's default.
+const defaultMaxRedirects = 10
+
+// httpClient returns the HTTP client requests are made with. It applies the
+// redirect policy, and uses its own transport when ClientCert or RootCAs is
+// set.
+func (w *WebRepository) httpClient() *http.Client {
+	w.clientOnce.Do(func() {
+		w.client = &http.Client{CheckRedirect: w.checkRedirect}
+		if w.ClientCert == nil && w.RootCAs == nil {
+			return
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: w.RootCAs, MinVersion: tls.VersionTLS12}
+		if w.ClientCert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*w.ClientCert}
+		}
+		w.client.Transport = transport
+	})
+	return w.client
+}
+
+// checkRedirect implements the redirect policy set by RejectRedirects and
+// MaxRedirects. Rejected redirects return the 3xx response itself, which
+// fails the refresh.
+func (w *WebRepository) checkRedirect(req *http.Request, via []*http.Request) error {
+	if w.RejectRedirects {
+		return http.ErrUseLastResponse
+	}
+	limit := w.MaxRedirects
+	if limit <= 0 {
+		limit = defaultMaxRedirects
+	}
+	if len(via) > limit {
+		return fmt.Errorf("stopped after %d redirects", limit)
+	}
+	return nil
 }
 
 // GetName returns the name of the configuration source.
@@ -26,6 +84,11 @@ func (w *WebRepository) GetName() string {
 	return w.Name
 }
 
+// Type returns the repository type.
+func (w *WebRepository) Type() string {
+	return TypeWeb
+}
+
 // GetData returns the configuration data as a map of configuration names to their respective models.
 func (w *WebRepository) GetData(configName string) (config interface{}, isPresent bool) {
 	w.RLock()
@@ -34,6 +97,20 @@ func (w *WebRepository) GetData(configName string) (config interface{}, isPresen
 	return config, isPresent
 }
 
+// GetAllData returns a deep copy of the decoded configuration map.
+func (w *WebRepository) GetAllData() map[string]interface{} {
+	w.RLock()
+	defer w.RUnlock()
+	return deepcopy.Map(w.data)
+}
+
+// Keys returns the top-level configuration names, without copying the data.
+func (w *WebRepository) Keys() []string {
+	w.RLock()
+	defer w.RUnlock()
+	return mapKeys(w.data)
+}
+
 // GetRawData returns the raw data of the YAML configuration file.
 func (w *WebRepository) GetRawData() []byte {
 	w.RLock()
@@ -41,9 +118,29 @@ func (w *WebRepository) GetRawData() []byte {
 	return w.rawData
 }
 
+// ETag returns the ETag of the response the current data was decoded from,
+// or "" if the server sent none.
+func (w *WebRepository) ETag() string {
+	w.RLock()
+	defer w.RUnlock()
+	return w.etag
+}
+
 // Refresh fetches the YAML file from the remote HTTP endpoint (web URL),
-// unmarshal it into the data map.
+// unmarshal it into the data map. Once a response carried an ETag it is sent
+// back as If-None-Match, and a 304 Not Modified keeps the current data without
+// downloading or decoding it again.
+// With CacheDir set, the last good data is cached on disk, see DiskCache.
 func (w *WebRepository) Refresh() error {
+	return w.cached(w, w.DecodeOptions, w.URL.String(), w.refresh, func(data map[string]interface{}, rawData []byte) {
+		w.Lock()
+		w.data, w.rawData = data, rawData
+		w.Unlock()
+	})
+}
+
+// refresh is Refresh without the DiskCache.
+func (w *WebRepository) refresh() error {
 	ctx := context.Background()
 
 	// Create an HTTP request to fetch the YAML file from the remote web URL.
@@ -58,11 +155,19 @@ func (w *WebRepository) Refresh() error {
 		request.Header.Set("X-API-Key", w.APIKey)
 	}
 
+	// Ask for the body only if it changed since the last refresh
+	w.RLock()
+	etag := w.etag
+	w.RUnlock()
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
 	// Perform the HTTP request to get the YAML file content.
-	resp, err := http.DefaultClient.Do(request)
+	resp, err := w.httpClient().Do(request)
 	if err != nil {
 		logrus.Debug("error doing request")
-		return err
+		return withKind(networkKind(err), err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -70,17 +175,32 @@ func (w *WebRepository) Refresh() error {
 			logrus.WithError(err).Debug("error closing response body")
 		}
 	}(resp.Body)
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return nil
+	}
+	if location, err := resp.Location(); err == nil && resp.StatusCode >= 300 && resp.StatusCode <= 399 {
+		return fmt.Errorf("repository %q: %s redirected to %s, unset RejectRedirects to follow it", w.Name, w.URL.Redacted(), location.Redacted())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("repository %q: unexpected status %d from %s", w.Name, resp.StatusCode, w.URL.Redacted())
+		return withKind(statusKind(resp.StatusCode), err)
+	}
 
 	// Read the file content from the response body.
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.Debug("error reading file")
-		return err
+		return withKind(networkKind(err), err)
+	}
+
+	// Decompress bodies the transport left gzipped
+	data, err = gunzip(data)
+	if err != nil {
+		return withKind(ErrParse, fmt.Errorf("repository %q: error decompressing %s: %w", w.Name, w.URL.Redacted(), err))
 	}
 
 	// Unmarshal to temp variable outside lock to prevent data corruption on error
-	var tempData map[string]interface{}
-	err = yaml.Unmarshal(data, &tempData)
+	tempData, data, err := w.decode(w.Name, w.URL.Redacted(), data)
 	if err != nil {
 		logrus.Debug("error unmarshalling file")
 		return err
@@ -90,7 +210,94 @@ func (w *WebRepository) Refresh() error {
 	w.Lock()
 	w.data = tempData
 	w.rawData = data
+	w.etag = resp.Header.Get("ETag")
 	w.Unlock()
 
 	return nil
 }
+
+// maxGzipLayers bounds how many nested gzip layers gunzip removes.
+const maxGzipLayers = 3
+
+// gunzip decompresses data while it starts with the gzip magic number. Go's
+// transport only decompresses responses when it set Accept-Encoding itself,
+// so a body can still be gzipped when the header was set explicitly, the
+// server compressed it twice, or the URL points at a .gz file. YAML never
+// starts with the magic number, so plain bodies are returned unchanged.
+func gunzip(data []byte) ([]byte, error) {
+	for i := 0; i < maxGzipLayers && isGzip(data); i++ {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// isGzip reports whether data starts with the gzip magic number.
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// WaitForChange long-polls WatchURL with the hash of the content last
+// fetched. The server holds the request until its content hash differs or
+// its watch timeout expires, so this returns true as soon as new content is
+// available.
+func (w *WebRepository) WaitForChange(ctx context.Context) (bool, error) {
+	if w.WatchURL == nil {
+		return false, errors.New("watch URL not configured")
+	}
+
+	watchURL := *w.WatchURL
+	query := watchURL.Query()
+	query.Set("hash", w.watchHash())
+	watchURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, watchURL.String(), nil)
+	if err != nil {
+		logrus.Debug("error creating watch request")
+		return false, err
+	}
+	if w.APIKey != "" {
+		request.Header.Set("X-API-Key", w.APIKey)
+	}
+
+	resp, err := w.httpClient().Do(request)
+	if err != nil {
+		logrus.Debug("error doing watch request")
+		return false, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logrus.WithError(err).Debug("error closing response body")
+		}
+	}(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotModified:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d from watch endpoint", resp.StatusCode)
+	}
+}
+
+// watchHash returns the hash WaitForChange reports to the server: the ETag
+// of the last response, which a config server sets to its content hash, or
+// the hash of the raw data if the server sent none. The raw data's own hash
+// differs from the server's whenever decoding changed it, e.g. with RootKey,
+// Template or Transforms, which would report a change on every watch.
+func (w *WebRepository) watchHash() string {
+	w.RLock()
+	defer w.RUnlock()
+	if etag := strings.Trim(strings.TrimPrefix(w.etag, "W/"), `"`); etag != "" {
+		return etag
+	}
+	return ContentHash(w.rawData)
+}
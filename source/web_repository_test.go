@@ -1,10 +1,25 @@
 package source
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestWebRepositoryRefresh tests basic refresh functionality
@@ -189,10 +204,8 @@ func TestWebRepositoryAPIKeyAuthFailure(t *testing.T) {
 	}
 
 	err := repo.Refresh()
-	// The refresh should succeed (HTTP request completes) but with error response body
-	// which will cause YAML unmarshal to fail since "Unauthorized\n" is not valid YAML
-	if err == nil {
-		t.Error("Expected error with invalid API key")
+	if !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission with invalid API key, got: %v", err)
 	}
 }
 
@@ -250,3 +263,442 @@ func TestWebRepositoryGetDataMissing(t *testing.T) {
 		t.Error("Expected 'nonexistent' key to not exist")
 	}
 }
+
+// TestWebRepositoryWaitForChange tests long-polling the watch endpoint with the current hash
+func TestWebRepositoryWaitForChange(t *testing.T) {
+	const body = "key: value\n"
+	receivedAPIKey := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch/test" {
+			w.Write([]byte(body))
+			return
+		}
+		receivedAPIKey = r.Header.Get("X-API-Key")
+		if r.URL.Query().Get("hash") == ContentHash([]byte(body)) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"hash":"new"}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	watchURL, _ := url.Parse(server.URL + "/watch/test")
+	repo := &WebRepository{
+		Name:     "test",
+		URL:      serverURL,
+		WatchURL: watchURL,
+		APIKey:   "secret-api-key",
+	}
+
+	// Before the first refresh the local hash differs from the server's
+	changed, err := repo.WaitForChange(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !changed {
+		t.Error("Expected change before first refresh")
+	}
+	if receivedAPIKey != "secret-api-key" {
+		t.Errorf("Expected X-API-Key to be 'secret-api-key', got '%s'", receivedAPIKey)
+	}
+
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	changed, err = repo.WaitForChange(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if changed {
+		t.Error("Expected no change after refresh")
+	}
+}
+
+// TestWebRepositoryWaitForChangeETag tests that watching reports the server's ETag rather than the hash of the decoded data
+func TestWebRepositoryWaitForChangeETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch/test" {
+			w.Header().Set("ETag", `"server-hash"`)
+			w.Write([]byte("teams:\n  payments:\n    key: value\n"))
+			return
+		}
+		if r.URL.Query().Get("hash") == "server-hash" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"hash":"server-hash"}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	watchURL, _ := url.Parse(server.URL + "/watch/test")
+	repo := &WebRepository{
+		Name:          "test",
+		URL:           serverURL,
+		WatchURL:      watchURL,
+		DecodeOptions: DecodeOptions{RootKey: "teams.payments"},
+	}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	changed, err := repo.WaitForChange(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if changed {
+		t.Error("Expected no change when the server's content matches the ETag")
+	}
+}
+
+// TestWebRepositoryWaitForChangeWithoutURL tests that watching requires a WatchURL
+func TestWebRepositoryWaitForChangeWithoutURL(t *testing.T) {
+	repo := &WebRepository{Name: "test"}
+	if _, err := repo.WaitForChange(context.Background()); err == nil {
+		t.Error("Expected error without WatchURL")
+	}
+}
+
+// TestWebRepositoryMalformedYAMLNamesSource tests that decode errors name the repository and URL
+func TestWebRepositoryMalformedYAMLNamesSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("key: value\nlist: [a, b\n"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL + "/config.yaml")
+	repo := &WebRepository{
+		Name: "web-repo",
+		URL:  serverURL,
+	}
+
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error for malformed YAML")
+	}
+	for _, want := range []string{"web-repo", serverURL.String(), "line"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestWebRepositoryMalformedKeepsData tests that a malformed response does not replace the last good data
+func TestWebRepositoryMalformedKeepsData(t *testing.T) {
+	var content atomic.Value
+	content.Store("key: value\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL + "/config.yaml")
+	repo := &WebRepository{Name: "web-repo", URL: serverURL}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content.Store("key: [value\n")
+	if err := repo.Refresh(); err == nil {
+		t.Fatal("Expected error for malformed YAML")
+	}
+	if val, _ := repo.GetData("key"); val != "value" {
+		t.Errorf("Expected the last good data to survive, got %v", val)
+	}
+	if raw := string(repo.GetRawData()); raw != "key: value\n" {
+		t.Errorf("Expected the last good raw data to survive, got %q", raw)
+	}
+}
+
+// gzipBytes compresses data with gzip.
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestWebRepositoryGzip tests that gzipped bodies are decompressed before decoding
+func TestWebRepositoryGzip(t *testing.T) {
+	content := []byte("key: value\n")
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"content encoding", "gzip", gzipBytes(t, content)},
+		{"gz file", "", gzipBytes(t, content)},
+		{"double gzip", "gzip", gzipBytes(t, gzipBytes(t, content))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				} else {
+					w.Header().Set("Content-Type", "application/gzip")
+				}
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			serverURL, _ := url.Parse(server.URL + "/config.yaml.gz")
+			repo := &WebRepository{Name: "test", URL: serverURL}
+			if err := repo.Refresh(); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if val, _ := repo.GetData("key"); val != "value" {
+				t.Errorf("Expected 'value', got '%v'", val)
+			}
+			if raw := repo.GetRawData(); string(raw) != string(content) {
+				t.Errorf("Expected decompressed raw data, got %q", raw)
+			}
+		})
+	}
+}
+
+// TestWebRepositoryCorruptGzip tests that a truncated gzip body fails the refresh
+func TestWebRepositoryCorruptGzip(t *testing.T) {
+	body := gzipBytes(t, []byte("key: value\n"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body[:len(body)/2])
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	repo := &WebRepository{Name: "test", URL: serverURL}
+	err := repo.Refresh()
+	if err == nil || !strings.Contains(err.Error(), "decompressing") {
+		t.Errorf("Expected decompression error, got: %v", err)
+	}
+}
+
+// TestWebRepositoryMultipleDocuments tests that a multi-document body is merged with later documents winning
+func TestWebRepositoryMultipleDocuments(t *testing.T) {
+	body := "key: value\nshared: first\n---\nshared: second\nother: 1\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	repo := &WebRepository{Name: "test", URL: serverURL}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for key, want := range map[string]interface{}{"key": "value", "shared": "second", "other": 1} {
+		if val, _ := repo.GetData(key); val != want {
+			t.Errorf("Expected %s = %v, got %v", key, want, val)
+		}
+	}
+	if string(repo.GetRawData()) != body {
+		t.Errorf("Expected raw data to match, got: %s", string(repo.GetRawData()))
+	}
+}
+
+// newClientCert returns a self-signed client certificate for commonName.
+func newClientCert(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// TestWebRepositoryClientCert tests mutual TLS against a server that requires client certificates
+func TestWebRepositoryClientCert(t *testing.T) {
+	cert := newClientCert(t, "config-client")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert.Leaf)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("client: " + r.TLS.PeerCertificates[0].Subject.CommonName + "\n"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	serverURL, _ := url.Parse(server.URL)
+
+	anonymous := &WebRepository{Name: "test", URL: serverURL, RootCAs: rootCAs}
+	if err := anonymous.Refresh(); err == nil {
+		t.Error("Expected error without a client certificate")
+	}
+
+	repo := &WebRepository{Name: "test", URL: serverURL, RootCAs: rootCAs, ClientCert: &cert}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("client"); val != "config-client" {
+		t.Errorf("Expected the server to verify the client certificate, got %v", val)
+	}
+}
+
+// TestWebRepositoryErrorKinds tests that failed responses are tagged with their kind
+func TestWebRepositoryErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusNotFound, "not found", ErrNotFound},
+		{http.StatusUnauthorized, "unauthorized", ErrPermission},
+		{http.StatusForbidden, "forbidden", ErrPermission},
+		{http.StatusServiceUnavailable, "unavailable", ErrUnreachable},
+		{http.StatusOK, "key: [value", ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			serverURL, _ := url.Parse(server.URL)
+			repo := &WebRepository{Name: "test", URL: serverURL}
+			if err := repo.Refresh(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverURL, _ := url.Parse(server.URL)
+	server.Close()
+	repo := &WebRepository{Name: "test", URL: serverURL}
+	if err := repo.Refresh(); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected ErrUnreachable for a closed server, got: %v", err)
+	}
+}
+
+// TestWebRepositoryConditionalRefresh tests that refreshes send the last ETag and keep the data on 304
+func TestWebRepositoryConditionalRefresh(t *testing.T) {
+	var mu sync.Mutex
+	body := "version: 1\n"
+	var notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := `"` + ContentHash([]byte(body)) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	repo := &WebRepository{Name: "test", URL: serverURL}
+	for i := 0; i < 3; i++ {
+		if err := repo.Refresh(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if notModified != 2 {
+		t.Errorf("Expected 2 conditional hits, got %d", notModified)
+	}
+	if val, _ := repo.GetData("version"); val != 1 {
+		t.Errorf("Expected version 1 after 304, got %v", val)
+	}
+	if string(repo.GetRawData()) != "version: 1\n" {
+		t.Errorf("Expected raw data kept after 304, got %q", repo.GetRawData())
+	}
+
+	mu.Lock()
+	body = "version: 2\n"
+	mu.Unlock()
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := repo.GetData("version"); val != 2 {
+		t.Errorf("Expected version 2 after change, got %v", val)
+	}
+}
+
+// TestWebRepositoryRedirects tests that redirects are followed unless RejectRedirects is set
+func TestWebRepositoryRedirects(t *testing.T) {
+	var redirect atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if redirect.Load() {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Write([]byte("key: value\n"))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("key: login page\n"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	configURL, _ := url.Parse(server.URL + "/config.yaml")
+
+	// Followed by default
+	redirect.Store(true)
+	following := &WebRepository{Name: "web-repo", URL: configURL}
+	if err := following.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if val, _ := following.GetData("key"); val != "login page" {
+		t.Errorf("Expected the redirect target's data, got %v", val)
+	}
+
+	// Rejected with RejectRedirects, keeping the last good data
+	redirect.Store(false)
+	repo := &WebRepository{Name: "web-repo", URL: configURL, RejectRedirects: true}
+	if err := repo.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	redirect.Store(true)
+	err := repo.Refresh()
+	if err == nil {
+		t.Fatal("Expected error for a rejected redirect")
+	}
+	if !strings.Contains(err.Error(), "/login") {
+		t.Errorf("Expected error to name the redirect target, got: %v", err)
+	}
+	if val, _ := repo.GetData("key"); val != "value" {
+		t.Errorf("Expected the last good data to survive, got %v", val)
+	}
+
+	// Bounded by MaxRedirects
+	loopURL, _ := url.Parse(server.URL + "/loop")
+	looping := &WebRepository{Name: "web-repo", URL: loopURL, MaxRedirects: 3}
+	if err := looping.Refresh(); err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Errorf("Expected the redirect limit to stop the refresh, got: %v", err)
+	}
+}